@@ -1,10 +1,8 @@
 package sdk
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
 	"io"
-	"net/http"
 )
 
 type AutomationTimingMode string
@@ -32,25 +30,26 @@ type Automation struct {
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
 - ErrPermissionDenied
 - PrepareRequest errors
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) ListAutomations() ([]Automation, error) {
-	if !c.ready {
-		return nil, ErrNotInitialized
-	}
-	req, err := c.prepareRequest("/api/automation/list/personal", Get, nil)
-	if err != nil {
+	return c.ListAutomationsContext(context.Background())
+}
+
+// Same as `ListAutomations`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) ListAutomationsContext(ctx context.Context) ([]Automation, error) {
+	if err := c.checkReady(); err != nil {
 		return nil, err
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := c.send(ctx, "ListAutomations", "/api/automation/list/personal", Get, nil)
 	if err != nil {
-		return nil, ErrConnFailed
+		return nil, err
 	}
 	defer res.Body.Close()
 	switch res.StatusCode {
@@ -60,16 +59,16 @@ func (c *Connection) ListAutomations() ([]Automation, error) {
 			return nil, ErrReadResponseBody
 		}
 		var parsedBody []Automation
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return nil, ErrReadResponseBody
+		if err := c.decode("ListAutomations", resBody, &parsedBody); err != nil {
+			return nil, err
 		}
 		return parsedBody, nil
 	case 401:
-		return nil, ErrInvalidCredentials
+		return nil, newAPIError(res, ErrInvalidCredentials)
 	case 422:
-		return nil, ErrUnprocessableEntity
+		return nil, newAPIError(res, ErrUnprocessableEntity)
 	case 403:
-		return nil, ErrPermissionDenied
+		return nil, newAPIError(res, ErrPermissionDenied)
 	}
-	return nil, fmt.Errorf("unknown response code: %s", res.Status)
+	return nil, newAPIError(res, ErrUnknownResponseCode)
 }
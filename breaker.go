@@ -0,0 +1,229 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The state of a connection's circuit breaker
+type CircuitState uint8
+
+const (
+	// Requests are sent normally, failures are counted
+	CircuitClosed CircuitState = iota
+	// Requests fail immediately with `ErrCircuitOpen` without being sent
+	CircuitOpen
+	// The server is probed using `HealthCheck` in order to decide whether the circuit can be closed again
+	CircuitHalfOpen
+)
+
+// Returns a human-readable name of the circuit state, used for logging
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(s))
+	}
+}
+
+// Specifies when the circuit breaker of a connection opens and when it probes the server again
+// A request fails if it returned `ErrConnFailed` or if the server responded with `503 Service Unavailable`
+// Requests which exceed their own timeout, for example a long-running Homescript, do not count as failures
+// Every attempt counts, including retries, login, health and version requests are not affected by the circuit breaker
+type CircuitBreakerPolicy struct {
+	// The number of consecutive failures after which the circuit opens
+	FailureThreshold int
+	// How long the circuit stays open before the server is probed
+	OpenDuration time.Duration
+	// Is called on every state transition, may be called concurrently by multiple goroutines
+	OnStateChange func(from CircuitState, to CircuitState)
+}
+
+// Returns a circuit breaker policy which is suitable for most applications
+// Opens after 5 consecutive failures and probes the server every 30 seconds
+func DefaultCircuitBreakerPolicy() CircuitBreakerPolicy {
+	return CircuitBreakerPolicy{
+		FailureThreshold: 5,
+		OpenDuration:     30 * time.Second,
+	}
+}
+
+// Stops sending requests while the Smarthome server is unreachable or degraded
+// Instead of waiting for every request to fail individually, requests fail fast with `ErrCircuitOpen`
+// Once `OpenDuration` has elapsed, the next request probes the server using `HealthCheck`
+// If failover URLs are configured, every server is probed and the connection switches to the first available one
+// If the server is healthy or only partially degraded, the circuit is closed and the request is sent
+// By default, no circuit breaker is used
+func WithCircuitBreaker(policy CircuitBreakerPolicy) Option {
+	return func(o *options) error {
+		if policy.FailureThreshold < 1 || policy.OpenDuration <= 0 {
+			return fmt.Errorf("%w: circuit breaker policy contains invalid values", ErrInvalidOption)
+		}
+		o.circuitBreaker = &policy
+		return nil
+	}
+}
+
+// Tracks the state of a connection's circuit breaker
+type breaker struct {
+	policy CircuitBreakerPolicy
+	// Protects every following field
+	mu    sync.Mutex
+	state CircuitState
+	// The number of consecutive failures while the circuit is closed
+	failures int
+	// The time at which the circuit was last opened
+	openedAt time.Time
+}
+
+// Used internally in order to create the circuit breaker of a connection
+// Returns nil if no circuit breaker has been configured
+func (o options) newBreaker() *breaker {
+	if o.circuitBreaker == nil {
+		return nil
+	}
+	return &breaker{policy: *o.circuitBreaker}
+}
+
+// Returns the current state of the connection's circuit breaker
+// Always returns `CircuitClosed` if no circuit breaker is used
+func (c *Connection) CircuitState() CircuitState {
+	if c.breaker == nil {
+		return CircuitClosed
+	}
+	c.breaker.mu.Lock()
+	defer c.breaker.mu.Unlock()
+	return c.breaker.state
+}
+
+// Used internally in order to change the state of the circuit breaker, `mu` must be held
+// Returns a function which notifies the callback, it must be called after `mu` has been released
+func (b *breaker) transition(to CircuitState) func() {
+	from := b.state
+	b.state = to
+	if to == CircuitOpen {
+		b.openedAt = time.Now()
+	}
+	b.failures = 0
+	if from == to || b.policy.OnStateChange == nil {
+		return func() {}
+	}
+	return func() { b.policy.OnStateChange(from, to) }
+}
+
+// Used internally in order to close the circuit, for example after switching to another server
+func (c *Connection) resetBreaker() {
+	b := c.breaker
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	notify := b.transition(CircuitClosed)
+	b.mu.Unlock()
+	notify()
+}
+
+// Used internally in order to check whether a request may be sent
+// Probes the server if the circuit has been open for long enough, only one goroutine probes at a time
+/** Errors
+- nil
+- ErrCircuitOpen
+- ErrCanceled
+*/
+func (c *Connection) allow(ctx context.Context) error {
+	b := c.breaker
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	switch b.state {
+	case CircuitClosed:
+		b.mu.Unlock()
+		return nil
+	case CircuitHalfOpen:
+		// Another goroutine is already probing the server
+		b.mu.Unlock()
+		return ErrCircuitOpen
+	}
+	if time.Since(b.openedAt) < b.policy.OpenDuration {
+		b.mu.Unlock()
+		return ErrCircuitOpen
+	}
+	notify := b.transition(CircuitHalfOpen)
+	b.mu.Unlock()
+	notify()
+
+	healthy, err := c.probe(ctx)
+	if errors.Is(err, ErrCanceled) {
+		// The probe was not completed, the next request probes again
+		b.mu.Lock()
+		openedAt := b.openedAt
+		notify = b.transition(CircuitOpen)
+		b.openedAt = openedAt
+		b.mu.Unlock()
+		notify()
+		return err
+	}
+	b.mu.Lock()
+	if healthy {
+		notify = b.transition(CircuitClosed)
+	} else {
+		notify = b.transition(CircuitOpen)
+	}
+	b.mu.Unlock()
+	notify()
+	if !healthy {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// Used internally in order to check whether the circuit can be closed again
+// If failover URLs are configured, every server is probed and the connection switches to the first available one
+func (c *Connection) probe(ctx context.Context) (bool, error) {
+	if len(c.endpoints) > 1 {
+		_, err := c.failover(ctx, c.activeIndex())
+		return err == nil, err
+	}
+	status, err := c.HealthCheckContext(ctx)
+	return err == nil && (status == StatusHealthy || status == StatusPartiallyDegraded), err
+}
+
+// Used internally in order to record the outcome of a request
+// Cancellation, requests which exceeded their own timeout and errors returned by middlewares are neither a failure nor a success
+func (c *Connection) recordOutcome(res *http.Response, err error) {
+	b := c.breaker
+	if b == nil {
+		return
+	}
+	failed := (errors.Is(err, ErrConnFailed) && !isTimeout(err)) || (err == nil && res.StatusCode == http.StatusServiceUnavailable)
+	if !failed && err != nil {
+		return
+	}
+	b.mu.Lock()
+	if b.state != CircuitClosed {
+		b.mu.Unlock()
+		return
+	}
+	if !failed {
+		b.failures = 0
+		b.mu.Unlock()
+		return
+	}
+	b.failures++
+	notify := func() {}
+	if b.failures >= b.policy.FailureThreshold {
+		notify = b.transition(CircuitOpen)
+	}
+	b.mu.Unlock()
+	notify()
+}
@@ -0,0 +1,188 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var down atomic.Bool
+	var requests atomic.Int32
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	r.HandleFunc("/api/switch/list/all", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("[]"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var mu sync.Mutex
+	var transitions []CircuitState
+	c, err := NewConnection(ts.URL, AuthMethodNone, WithCircuitBreaker(CircuitBreakerPolicy{
+		FailureThreshold: 2,
+		OpenDuration:     50 * time.Millisecond,
+		OnStateChange: func(from CircuitState, to CircuitState) {
+			mu.Lock()
+			defer mu.Unlock()
+			transitions = append(transitions, to)
+		},
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+
+	_, err = c.GetAllSwitches()
+	assert.NoError(t, err)
+
+	// Consecutive failures open the circuit
+	down.Store(true)
+	for i := 0; i < 2; i++ {
+		_, err = c.GetAllSwitches()
+		assert.ErrorIs(t, err, ErrServiceUnavailable)
+	}
+	assert.Equal(t, CircuitOpen, c.CircuitState())
+
+	// Requests fail fast while the circuit is open
+	_, err = c.GetAllSwitches()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(3), requests.Load())
+
+	// The probe fails as long as the server is degraded
+	time.Sleep(60 * time.Millisecond)
+	_, err = c.GetAllSwitches()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, CircuitOpen, c.CircuitState())
+	assert.Equal(t, int32(3), requests.Load())
+
+	// A successful probe closes the circuit
+	down.Store(false)
+	_, err = c.GetAllSwitches()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	time.Sleep(60 * time.Millisecond)
+	_, err = c.GetAllSwitches()
+	assert.NoError(t, err)
+	assert.Equal(t, CircuitClosed, c.CircuitState())
+	assert.Equal(t, int32(4), requests.Load())
+
+	mu.Lock()
+	assert.Equal(t, []CircuitState{
+		CircuitOpen,
+		CircuitHalfOpen,
+		CircuitOpen,
+		CircuitHalfOpen,
+		CircuitClosed,
+	}, transitions)
+	mu.Unlock()
+}
+
+func TestCircuitBreakerConnFailed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	}))
+
+	c, err := NewConnection(ts.URL, AuthMethodNone, WithCircuitBreaker(CircuitBreakerPolicy{
+		FailureThreshold: 1,
+		OpenDuration:     time.Hour,
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+	ts.Close()
+
+	_, err = c.GetAllSwitches()
+	assert.ErrorIs(t, err, ErrConnFailed)
+	_, err = c.GetAllSwitchesContext(context.Background())
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
+
+func TestCircuitBreakerOptions(t *testing.T) {
+	_, err := NewConnection("http://localhost", AuthMethodNone, WithCircuitBreaker(CircuitBreakerPolicy{}))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewConnection("http://localhost", AuthMethodNone, WithCircuitBreaker(DefaultCircuitBreakerPolicy()))
+	assert.NoError(t, err)
+}
+
+func TestCircuitBreakerIgnoresTimeouts(t *testing.T) {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	// A long-running Homescript and a slow listing, the server itself is available
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		// The body must be consumed in order to notice that the client has given up
+		_, _ = io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}
+	r.HandleFunc("/api/homescript/run/live", slow)
+	r.HandleFunc("/api/switch/list/all", slow)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodNone,
+		WithDefaultTimeout(100*time.Millisecond),
+		WithCircuitBreaker(CircuitBreakerPolicy{
+			FailureThreshold: 1,
+			OpenDuration:     time.Hour,
+		}),
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+
+	// The timeout of a Homescript function is not a failure of the server
+	for i := 0; i < 3; i++ {
+		_, err = c.RunHomescriptCode("sleep(1)", nil, 20*time.Millisecond)
+		assert.ErrorIs(t, err, ErrConnFailed)
+	}
+	assert.Equal(t, CircuitClosed, c.CircuitState())
+
+	// Neither is the default timeout of the connection
+	_, err = c.GetAllSwitches()
+	assert.ErrorIs(t, err, ErrConnFailed)
+	assert.Equal(t, CircuitClosed, c.CircuitState())
+
+	// A deadline of the caller is reported as cancellation and is not a failure either
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = c.GetAllSwitchesContext(ctx)
+	assert.ErrorIs(t, err, ErrCanceled)
+	assert.Equal(t, CircuitClosed, c.CircuitState())
+}
@@ -0,0 +1,70 @@
+package sdk
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver"
+)
+
+// A feature of the Smarthome server which is not available in every supported version
+type Feature string
+
+// Specifies which Smarthome versions support a feature
+// The constraints use the syntax of `github.com/Masterminds/semver`
+// Every constraint must name the first Smarthome release whose changelog lists the feature
+// Features which every version since `MinSmarthomeVersion` supports, like Homescript linting, arguments and workspaces, are not listed
+var capabilities = map[Feature]string{}
+
+// Is returned when a function requires a feature which the connected Smarthome server does not support
+// Matches `ErrFeatureUnsupported`
+type FeatureUnsupportedError struct {
+	// The feature which is required
+	Feature Feature
+	// The version of the connected Smarthome server
+	ServerVersion string
+	// The versions which support the feature
+	Required string
+}
+
+func (e *FeatureUnsupportedError) Error() string {
+	return fmt.Sprintf("%s: `%s` requires Smarthome %s, but the server runs %s", ErrFeatureUnsupported, e.Feature, e.Required, e.ServerVersion)
+}
+
+func (e *FeatureUnsupportedError) Is(target error) bool {
+	return target == ErrFeatureUnsupported
+}
+
+// Reports whether the connected Smarthome server supports the specified feature
+// Returns false if the connection has not been initialized yet, because the server's version is unknown
+// Unknown features are never supported
+func (c *Connection) Supports(feature Feature) bool {
+	return c.requireFeature(feature) == nil
+}
+
+// Used internally in order to fail early if the server does not support a feature
+/** Errors
+- nil
+- ErrNotInitialized
+- FeatureUnsupportedError
+*/
+func (c *Connection) requireFeature(feature Feature) error {
+	c.mu.RLock()
+	version := c.serverVersion
+	c.mu.RUnlock()
+	if version == nil {
+		return ErrNotInitialized
+	}
+	required, found := capabilities[feature]
+	if !found {
+		return &FeatureUnsupportedError{Feature: feature, ServerVersion: version.String(), Required: "an unknown version"}
+	}
+	constraint, err := semver.NewConstraint(required)
+	if err != nil {
+		// The capability table is static, this must not happen (tests)
+		panic(fmt.Sprintf("invalid capability constraint for `%s`: %s", feature, err.Error()))
+	}
+	if !constraint.Check(version) {
+		return &FeatureUnsupportedError{Feature: feature, ServerVersion: version.String(), Required: required}
+	}
+	return nil
+}
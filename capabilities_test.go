@@ -0,0 +1,74 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilityTable(t *testing.T) {
+	for feature, constraint := range capabilities {
+		_, err := semver.NewConstraint(constraint)
+		assert.NoError(t, err, feature)
+	}
+}
+
+func TestSupports(t *testing.T) {
+	// The table does not contain any feature yet, a test feature is registered instead
+	const feature = Feature("test-feature")
+	capabilities[feature] = ">= 0.2.5"
+	defer delete(capabilities, feature)
+
+	version := MinSmarthomeVersion
+	requests := 0
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   version,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/homescript/list/personal/complete", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte("[]"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodNone)
+	assert.NoError(t, err)
+	// The version is unknown before the connection is initialized
+	assert.False(t, c.Supports(feature))
+	assert.ErrorIs(t, c.requireFeature(feature), ErrNotInitialized)
+
+	assert.NoError(t, c.Connect())
+	assert.False(t, c.Supports(feature))
+	assert.False(t, c.Supports(Feature("unknown")))
+	err = c.requireFeature(feature)
+	assert.ErrorIs(t, err, ErrFeatureUnsupported)
+	var featureErr *FeatureUnsupportedError
+	assert.True(t, errors.As(err, &featureErr))
+	assert.Equal(t, feature, featureErr.Feature)
+	assert.Equal(t, MinSmarthomeVersion, featureErr.ServerVersion)
+	assert.Equal(t, ">= 0.2.5", featureErr.Required)
+
+	// Homescript arguments are available on every supported version
+	_, err = c.ListHomescriptWithArgs()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	version = "0.2.5"
+	c, err = NewConnection(ts.URL, AuthMethodNone)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+	assert.True(t, c.Supports(feature))
+}
@@ -1,8 +1,13 @@
 package sdk
 
 import (
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"sync"
+
+	"github.com/Masterminds/semver"
 )
 
 type AuthMethod uint8
@@ -17,8 +22,9 @@ const (
 
 	/** Cookie authentication relies on a cookie-store which sends a authentication cookie at every request
 	- Faster response-time: The server does not need to revalidate the user's credentials on every request
-	- Static: If the Smarthome server is restarted, the stored cookie becomes invalid and the communication breaks
-	- Not recommended for long-running applications
+	- Self-healing: If the Smarthome server is restarted, the stored cookie becomes invalid
+	  The connection detects the rejected cookie, logs in again using the stored credentials and retries the request once
+	- Re-login events can be observed using `WithReloginHook`
 	*/
 	AuthMethodCookiePassword
 	// Uses a token to connect instead of the username and password whilst using cookie authentication
@@ -32,12 +38,55 @@ const (
 	AuthMethodQueryPassword
 	// Uses a token to connect instead of the username and password whilst using query authentication
 	AuthMethodQueryToken
+
+	/** Header authentication sends the credentials in the `Authorization` header of every request
+	- Passwords are sent using HTTP basic authentication, tokens are sent as `Bearer` tokens
+	- Same semantics as URL-query authentication: the server revalidates the credentials on every request
+	- Secure: The credentials do not appear in URLs, which means that they are not written to proxy access logs
+	- Recommended for long-running applications
+	*/
+	AuthMethodHeaderPassword
+	// Uses a token to connect instead of the username and password whilst using header authentication
+	AuthMethodHeaderToken
 )
 
+// Returns a human-readable name of the authentication method, used for logging
+func (m AuthMethod) String() string {
+	switch m {
+	case AuthMethodNone:
+		return "none"
+	case AuthMethodCookiePassword:
+		return "cookie-password"
+	case AuthMethodCookieToken:
+		return "cookie-token"
+	case AuthMethodQueryPassword:
+		return "query-password"
+	case AuthMethodQueryToken:
+		return "query-token"
+	case AuthMethodHeaderPassword:
+		return "header-password"
+	case AuthMethodHeaderToken:
+		return "header-token"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(m))
+	}
+}
+
+// A connection to a Smarthome server
+// A connection is safe for concurrent use by multiple goroutines once it has been initialized
+// The exported fields are written during the login and must not be modified afterwards
 type Connection struct {
+	// Protects the session state: `credStore`, `credProvider`, `sessionCookie`, `tokenClientName`, `serverVersion`, `active`, `ready`, `closed` and `middlewares`
+	mu sync.RWMutex
+	// Serializes logins so that concurrent requests whose session was rejected only cause one re-login
+	loginMu sync.Mutex
 	// Stores credentials used by the SDK
 	credStore credStore
+	// If set, is consulted instead of `credStore` whenever credentials are required
+	credProvider CredentialProvider
 	// The base URL which will be used to create all request
+	// If failover URLs are configured, this is the primary server, which is not necessarily the active server
+	// Is never modified by the SDK, every request uses a copy of it
 	SmarthomeURL *url.URL
 	// Stores which authentication mode will be used
 	authMethod AuthMethod
@@ -49,10 +98,46 @@ type Connection struct {
 	tokenClientName string
 	// Used internally to specify if the connection is ready to be used
 	ready bool
+	// Is set by `Close`, a closed connection cannot be used anymore
+	closed bool
+	// Is closed by `Close` in order to stop background goroutines
+	done chan struct{}
 	// Stores the version of the Smarthome server in order to avoid using the `Version` function multiple times
 	SmarthomeVersion string
 	// Stores the GO version on which the Smarthome server runs on
 	SmarthomeGoVersion string
+	// The parsed version of the Smarthome server, used in order to check which features are supported
+	serverVersion *semver.Version
+	// The HTTP client which is shared by every request of this connection
+	client *http.Client
+	// The `User-Agent` header which is sent with every request
+	userAgent string
+	// Is called after the connection has attempted to obtain a new session cookie
+	reloginHook func(err error)
+	// Specifies how requests which failed due to transient errors are retried
+	retryPolicy RetryPolicy
+	// Wrap every request which is sent by this connection, added using `Use`
+	middlewares []Middleware
+	// Receives structured records about every request, nil disables logging
+	logger *slog.Logger
+	// If set, the session is saved to it after every successful login
+	sessionStore SessionStore
+	// Specifies how unknown fields in responses are handled
+	decodeMode DecodeMode
+	// Is called whenever a response contains unknown fields
+	unknownFieldsHandler func(endpoint string, fields []string)
+	// Enforce the limits of every endpoint class, is not modified after the connection has been created
+	limiters map[EndpointClass]*limiter
+	// Stops sending requests while the server is unavailable, nil if no circuit breaker is used
+	breaker *breaker
+	// The base URLs of every Smarthome server in the order of preference, starts with `SmarthomeURL`
+	endpoints []*url.URL
+	// The index of the server in `endpoints` which receives every request
+	active int
+	// Serializes failovers so that concurrent requests which failed only cause one failover
+	failoverMu sync.Mutex
+	// Is called whenever the connection switches to another server
+	failoverHook func(from string, to string, err error)
 }
 
 // Saves the username - password combination
@@ -0,0 +1,136 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The credentials which are used for authentication
+// Depending on the authentication method, either `Username` and `Password` or `Token` are used
+type Credentials struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// Is consulted whenever the connection needs credentials, meaning on every login and,
+// for URL-query and header authentication, on every request
+// Allows credentials to be rotated without creating a new connection
+// Implementations must be safe for concurrent use by multiple goroutines
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// Always provides the same credentials
+// Can be passed to `ProviderLogin` in order to use fixed credentials, `UserLogin` and `TokenLogin` store their credentials directly
+type StaticCredentialProvider struct {
+	Value Credentials
+}
+
+func (p StaticCredentialProvider) Credentials(ctx context.Context) (Credentials, error) {
+	return p.Value, nil
+}
+
+// Reads the credentials from environment variables on every call
+// Empty variable names are ignored, the matching credential is left empty
+type EnvCredentialProvider struct {
+	UsernameVar string
+	PasswordVar string
+	TokenVar    string
+}
+
+func (p EnvCredentialProvider) Credentials(ctx context.Context) (Credentials, error) {
+	var creds Credentials
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{
+		{p.UsernameVar, &creds.Username},
+		{p.PasswordVar, &creds.Password},
+		{p.TokenVar, &creds.Token},
+	} {
+		if field.name == "" {
+			continue
+		}
+		value, found := os.LookupEnv(field.name)
+		if !found {
+			return Credentials{}, fmt.Errorf("%w: environment variable `%s` is not set", ErrCredentialsUnavailable, field.name)
+		}
+		*field.value = value
+	}
+	return creds, nil
+}
+
+// Reads the credentials from files, for example from a mounted Kubernetes secret
+// Every file contains exactly one credential, surrounding whitespace is removed
+// The files are only read again if their modification time or size has changed
+// Empty paths are ignored, the matching credential is left empty
+// Must not be copied after first use
+type FileCredentialProvider struct {
+	UsernameFile string
+	PasswordFile string
+	TokenFile    string
+
+	// Caches the contents of the files
+	mu    sync.Mutex
+	cache map[string]cachedFile
+}
+
+// Stores the content of a file together with the information required to detect changes
+type cachedFile struct {
+	modTime time.Time
+	size    int64
+	content string
+}
+
+func (p *FileCredentialProvider) Credentials(ctx context.Context) (Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cache == nil {
+		p.cache = make(map[string]cachedFile)
+	}
+	var creds Credentials
+	for _, field := range []struct {
+		path  string
+		value *string
+	}{
+		{p.UsernameFile, &creds.Username},
+		{p.PasswordFile, &creds.Password},
+		{p.TokenFile, &creds.Token},
+	} {
+		if field.path == "" {
+			continue
+		}
+		content, err := p.read(field.path)
+		if err != nil {
+			return Credentials{}, err
+		}
+		*field.value = content
+	}
+	return creds, nil
+}
+
+// Used internally in order to read a file, unless it has not changed since the last time
+func (p *FileCredentialProvider) read(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrCredentialsUnavailable, err.Error())
+	}
+	if cached, found := p.cache[path]; found && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.content, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrCredentialsUnavailable, err.Error())
+	}
+	p.cache[path] = cachedFile{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		content: strings.TrimSpace(string(content)),
+	}
+	return p.cache[path].content, nil
+}
@@ -0,0 +1,137 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileCredentialProvider(t *testing.T) {
+	var validToken atomic.Value
+	validToken.Store("token-1")
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/login/token", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Token string `json:"token"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body.Token != validToken.Load() {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session"})
+		assert.NoError(t, json.NewEncoder(w).Encode(tokenLoginResponse{
+			Username:   "test",
+			TokenLabel: "file",
+		}))
+	})
+
+	r.HandleFunc("/api/power/set", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+validToken.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("token-1\n"), 0600))
+
+	c, err := NewConnection(ts.URL, AuthMethodHeaderToken)
+	assert.NoError(t, err)
+	assert.NoError(t, c.ProviderLogin(&FileCredentialProvider{TokenFile: tokenFile}))
+	assert.NoError(t, c.SetPower("s1", true))
+	username, err := c.GetUsername()
+	assert.NoError(t, err)
+	assert.Equal(t, "test", username)
+
+	// The token is rotated on the server, the old one is rejected until the file is updated
+	validToken.Store("token-rotated")
+	assert.ErrorIs(t, c.SetPower("s1", true), ErrInvalidCredentials)
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("token-rotated\n"), 0600))
+	assert.NoError(t, c.SetPower("s1", true))
+
+	// A missing file is reported as unavailable credentials
+	assert.NoError(t, os.Remove(tokenFile))
+	assert.ErrorIs(t, c.SetPower("s1", true), ErrCredentialsUnavailable)
+}
+
+func TestEnvCredentialProvider(t *testing.T) {
+	t.Setenv("SMARTHOME_TEST_USER", "user")
+	t.Setenv("SMARTHOME_TEST_PASSWORD", "password")
+
+	creds, err := EnvCredentialProvider{
+		UsernameVar: "SMARTHOME_TEST_USER",
+		PasswordVar: "SMARTHOME_TEST_PASSWORD",
+	}.Credentials(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Credentials{Username: "user", Password: "password"}, creds)
+
+	_, err = EnvCredentialProvider{TokenVar: "SMARTHOME_TEST_MISSING"}.Credentials(context.Background())
+	assert.ErrorIs(t, err, ErrCredentialsUnavailable)
+
+	creds, err = StaticCredentialProvider{Value: Credentials{Token: "token"}}.Credentials(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token", creds.Token)
+}
+
+func TestCredentialProviderCookieAuth(t *testing.T) {
+	t.Setenv("SMARTHOME_TEST_USER", "user")
+	t.Setenv("SMARTHOME_TEST_PASSWORD", "password")
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session"})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.HandleFunc("/api/switch/list/personal", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("session"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("[]"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodCookiePassword)
+	assert.NoError(t, err)
+	assert.NoError(t, c.ProviderLogin(EnvCredentialProvider{
+		UsernameVar: "SMARTHOME_TEST_USER",
+		PasswordVar: "SMARTHOME_TEST_PASSWORD",
+	}))
+
+	// The provider is only consulted for logging in, the session cookie remains usable
+	assert.NoError(t, os.Unsetenv("SMARTHOME_TEST_PASSWORD"))
+	_, err = c.GetPersonalSwitches()
+	assert.NoError(t, err)
+}
@@ -1,9 +1,8 @@
 package sdk
 
 import (
-	"encoding/json"
+	"context"
 	"io"
-	"net/http"
 )
 
 type DBStatus struct {
@@ -55,6 +54,7 @@ type HardwareNode struct {
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
@@ -63,18 +63,19 @@ type HardwareNode struct {
 - ErrPermissionDenied
 */
 func (c *Connection) GetDebugInfo() (info DebugInfoData, err error) {
-	if !c.ready {
-		return DebugInfoData{}, ErrNotInitialized
-	}
-	req, err := c.prepareRequest("/api/debug", Get, nil)
-	if err != nil {
+	return c.GetDebugInfoContext(context.Background())
+}
+
+// Same as `GetDebugInfo`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) GetDebugInfoContext(ctx context.Context) (info DebugInfoData, err error) {
+	if err := c.checkReady(); err != nil {
 		return DebugInfoData{}, err
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := c.send(ctx, "GetDebugInfo", "/api/debug", Get, nil)
 	if err != nil {
-		return DebugInfoData{}, ErrConnFailed
+		return DebugInfoData{}, err
 	}
+	defer res.Body.Close()
 	switch res.StatusCode {
 	case 200:
 		resBody, err := io.ReadAll(res.Body)
@@ -82,16 +83,16 @@ func (c *Connection) GetDebugInfo() (info DebugInfoData, err error) {
 			return DebugInfoData{}, ErrReadResponseBody
 		}
 		var parsedBody DebugInfoData
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return DebugInfoData{}, ErrReadResponseBody
+		if err := c.decode("GetDebugInfo", resBody, &parsedBody); err != nil {
+			return DebugInfoData{}, err
 		}
 		return parsedBody, nil
 	case 401:
-		return DebugInfoData{}, ErrInvalidCredentials
+		return DebugInfoData{}, newAPIError(res, ErrInvalidCredentials)
 	case 403:
-		return DebugInfoData{}, ErrPermissionDenied
+		return DebugInfoData{}, newAPIError(res, ErrPermissionDenied)
 	case 503:
-		return DebugInfoData{}, ErrServiceUnavailable
+		return DebugInfoData{}, newAPIError(res, ErrServiceUnavailable)
 	}
-	return DebugInfoData{}, nil
+	return DebugInfoData{}, newAPIError(res, ErrUnknownResponseCode)
 }
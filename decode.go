@@ -0,0 +1,208 @@
+package sdk
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Specifies how the connection handles fields in responses which are unknown to the SDK
+type DecodeMode uint8
+
+const (
+	/** Lenient decoding ignores unknown fields
+	- Forward-compatible: newer Smarthome servers may add fields without breaking the SDK
+	- Unknown fields can still be observed using `WithUnknownFieldsHandler`
+	- Is the default mode
+	*/
+	DecodeLenient DecodeMode = iota
+	/** Strict decoding rejects every response which contains unknown fields
+	- The request fails with an `UnknownFieldsError` which lists every unknown field
+	- Useful for contract tests which detect changes of the Smarthome API
+	*/
+	DecodeStrict
+)
+
+// Sets how responses which contain fields unknown to the SDK are handled
+// The default mode is `DecodeLenient`
+func WithDecodeMode(mode DecodeMode) Option {
+	return func(o *options) error {
+		if mode != DecodeLenient && mode != DecodeStrict {
+			return fmt.Errorf("%w: unknown decode mode %d", ErrInvalidOption, mode)
+		}
+		o.decodeMode = mode
+		return nil
+	}
+}
+
+// Registers a function which is called whenever a response contains fields unknown to the SDK
+// The endpoint is the name of the function which sent the request, the fields are sorted paths like `[].workspace`
+// The handler is called in both decode modes, before the request fails in strict mode
+func WithUnknownFieldsHandler(handler func(endpoint string, fields []string)) Option {
+	return func(o *options) error {
+		o.unknownFieldsHandler = handler
+		return nil
+	}
+}
+
+// Is returned in strict decode mode if a response contains fields unknown to the SDK
+// Matches `ErrUnknownFields` as well as `ErrReadResponseBody`
+type UnknownFieldsError struct {
+	// The name of the function which sent the request
+	Endpoint string
+	// The paths of the unknown fields, for example `[].workspace`
+	Fields []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrUnknownFields, e.Endpoint, strings.Join(e.Fields, ", "))
+}
+
+func (e *UnknownFieldsError) Is(target error) bool {
+	return target == ErrUnknownFields || target == ErrReadResponseBody
+}
+
+// Used internally in order to decode a JSON response according to the connection's decode mode
+/** Errors
+- nil
+- ErrReadResponseBody
+- UnknownFieldsError
+*/
+func (c *Connection) decode(endpoint string, data []byte, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrReadResponseBody
+	}
+	// Detecting unknown fields requires a second pass, which is skipped if nobody is interested
+	if c.decodeMode == DecodeLenient && c.unknownFieldsHandler == nil {
+		return nil
+	}
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return ErrReadResponseBody
+	}
+	set := make(map[string]struct{})
+	collectUnknownFields(raw, reflect.TypeOf(v), "", set)
+	if len(set) == 0 {
+		return nil
+	}
+	fields := make([]string, 0, len(set))
+	for field := range set {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	if c.unknownFieldsHandler != nil {
+		c.unknownFieldsHandler(endpoint, fields)
+	}
+	if c.decodeMode == DecodeStrict {
+		return &UnknownFieldsError{Endpoint: endpoint, Fields: fields}
+	}
+	return nil
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Used internally in order to compare a decoded JSON value with the Go type it was decoded into
+// Every object key which has no matching struct field is added to `set`
+// Array elements share the path `[]`, so that a field which is unknown in every element is only reported once
+func collectUnknownFields(value any, t reflect.Type, path string, set map[string]struct{}) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Types which decode themselves may accept arbitrary content
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+		fields := structFields(t)
+		for key, child := range object {
+			field, found := lookupField(fields, key)
+			if !found {
+				set[joinFieldPath(path, key)] = struct{}{}
+				continue
+			}
+			collectUnknownFields(child, field.Type, joinFieldPath(path, key), set)
+		}
+	case reflect.Slice, reflect.Array:
+		array, ok := value.([]any)
+		if !ok {
+			return
+		}
+		for _, child := range array {
+			collectUnknownFields(child, t.Elem(), path+"[]", set)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+		for key, child := range object {
+			collectUnknownFields(child, t.Elem(), joinFieldPath(path, key), set)
+		}
+	}
+}
+
+// Used internally in order to list the JSON names of a struct's fields, including the fields of embedded structs
+func structFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		embedded := field.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && name == "" && embedded.Kind() == reflect.Struct {
+			for embeddedName, embeddedField := range structFields(embedded) {
+				if _, found := fields[embeddedName]; !found {
+					fields[embeddedName] = embeddedField
+				}
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// Used internally in order to find the field which `encoding/json` would decode a key into
+// Like `encoding/json`, an exact match is preferred over a case-insensitive match
+func lookupField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	if field, found := fields[key]; found {
+		return field, true
+	}
+	for name, field := range fields {
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// Used internally in order to build the path of a nested field
+func joinFieldPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
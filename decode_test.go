@@ -0,0 +1,103 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeModes(t *testing.T) {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"version":"%s","goVersion":"go1.18","buildDate":"today"}`, MinSmarthomeVersion)
+	})
+
+	r.HandleFunc("/api/switch/list/all", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"id":"s1","name":"Lamp","roomId":"r","powerOn":true,"watts":10,"targetNode":"n1"},
+			{"id":"s2","name":"Fan","roomId":"r","powerOn":false,"watts":20,"targetNode":"n2","icon":"fan"}
+		]`))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	// Lenient mode is the default and ignores unknown fields
+	c, err := NewConnection(ts.URL, AuthMethodNone)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+	switches, err := c.GetAllSwitches()
+	assert.NoError(t, err)
+	assert.Len(t, switches, 2)
+
+	// The handler is called in lenient mode as well
+	reported := make(map[string][]string)
+	c, err = NewConnection(ts.URL, AuthMethodNone, WithUnknownFieldsHandler(func(endpoint string, fields []string) {
+		reported[endpoint] = fields
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+	_, err = c.GetAllSwitches()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		EndpointVersion:  {"buildDate"},
+		"GetAllSwitches": {"[].icon", "[].targetNode"},
+	}, reported)
+
+	// Strict mode rejects responses with unknown fields
+	c, err = NewConnection(ts.URL, AuthMethodNone, WithDecodeMode(DecodeStrict))
+	assert.NoError(t, err)
+	err = c.Connect()
+	assert.ErrorIs(t, err, ErrUnknownFields)
+	assert.ErrorIs(t, err, ErrReadResponseBody)
+	var fieldsErr *UnknownFieldsError
+	assert.True(t, errors.As(err, &fieldsErr))
+	assert.Equal(t, EndpointVersion, fieldsErr.Endpoint)
+	assert.Equal(t, []string{"buildDate"}, fieldsErr.Fields)
+
+	_, err = NewConnection(ts.URL, AuthMethodNone, WithDecodeMode(DecodeMode(42)))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+}
+
+func TestCollectUnknownFields(t *testing.T) {
+	type inner struct {
+		Known string `json:"known"`
+	}
+	type embedded struct {
+		Embedded string `json:"embedded"`
+	}
+	type outer struct {
+		embedded
+		Name     string `json:"name"`
+		Ignored  string `json:"-"`
+		Untagged string
+		Inner    *inner           `json:"inner"`
+		Items    []inner          `json:"items"`
+		ByKey    map[string]inner `json:"byKey"`
+		Raw      map[string]any   `json:"raw"`
+	}
+	set := make(map[string]struct{})
+	var raw any = map[string]any{
+		"embedded": "",
+		"NAME":     "",
+		"Ignored":  "",
+		"untagged": "",
+		"inner":    map[string]any{"known": "", "unknown": ""},
+		"items":    []any{map[string]any{"other": ""}},
+		"byKey":    map[string]any{"a": map[string]any{"known": "", "extra": ""}},
+		"raw":      map[string]any{"anything": ""},
+	}
+	collectUnknownFields(raw, reflect.TypeOf(&outer{}), "", set)
+	assert.Equal(t, map[string]struct{}{
+		"Ignored":       {},
+		"inner.unknown": {},
+		"items[].other": {},
+		"byKey.a.extra": {},
+	}, set)
+}
@@ -0,0 +1,82 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Sends a request to an arbitrary endpoint of the Smarthome API, for example one which is not wrapped by the SDK yet
+// The request has the same semantics as the built-in functions: authentication, headers, retries, re-logins and middlewares apply
+// The path is relative to the base URL, must be escaped and must not contain a query, for example `/api/user/data`
+// If `body` is not nil, it is encoded to JSON and sent as the request body
+// If `out` is not nil, a successful response is decoded into it according to the connection's decode mode
+// An empty response body leaves `out` unchanged
+// Unsuccessful status codes are reported as an `*APIError` which wraps the matching sentinel error
+/** Errors
+- nil
+- ErrNotInitialized
+- ErrConnectionClosed
+- ErrInvalidURL
+- ErrConnFailed
+- ErrReadResponseBody
+- UnknownFieldsError (only in strict decode mode)
+- ErrBadRequest
+- ErrInvalidCredentials
+- ErrPermissionDenied
+- ErrNotFound
+- ErrConflict
+- ErrUnprocessableEntity
+- ErrInternalServerError
+- ErrServiceUnavailable
+- ErrUnknownResponseCode
+- PrepareRequest errors
+*/
+func (c *Connection) Do(ctx context.Context, method HTTPMethod, path string, body any, out any) error {
+	if err := c.checkReady(); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, "?#") {
+		return fmt.Errorf("%w: `%s` is not an absolute path without a query", ErrInvalidURL, path)
+	}
+	res, err := c.send(ctx, EndpointDo, path, method, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return newAPIError(res, statusError(res.StatusCode))
+	}
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return ErrReadResponseBody
+	}
+	if out == nil || len(resBody) == 0 {
+		return nil
+	}
+	return c.decode(EndpointDo, resBody, out)
+}
+
+// Used internally in order to map an unsuccessful status code to the matching sentinel error
+func statusError(statusCode int) error {
+	switch statusCode {
+	case 400:
+		return ErrBadRequest
+	case 401:
+		return ErrInvalidCredentials
+	case 403:
+		return ErrPermissionDenied
+	case 404:
+		return ErrNotFound
+	case 409:
+		return ErrConflict
+	case 422:
+		return ErrUnprocessableEntity
+	case 500:
+		return ErrInternalServerError
+	case 503:
+		return ErrServiceUnavailable
+	}
+	return ErrUnknownResponseCode
+}
@@ -0,0 +1,106 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDo(t *testing.T) {
+	type userData struct {
+		Username string `json:"username"`
+		Forename string `json:"forename"`
+	}
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session"})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.HandleFunc("/api/user/data", func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "admin" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		switch r.Method {
+		case http.MethodGet:
+			assert.NoError(t, json.NewEncoder(w).Encode(userData{Username: "admin", Forename: "Admin"}))
+		case http.MethodPut:
+			var data userData
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&data))
+			if data.Forename == "" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				assert.NoError(t, json.NewEncoder(w).Encode(GenericResponse{Success: false, Message: "forename is empty"}))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodHeaderPassword)
+	assert.NoError(t, err)
+
+	// The connection must be initialized first
+	assert.ErrorIs(t, c.Do(context.Background(), Get, "/api/user/data", nil, nil), ErrNotInitialized)
+
+	assert.NoError(t, c.UserLogin("admin", "admin"))
+	c.Use(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, EndpointDo, EndpointName(req.Context()))
+			return next(req)
+		}
+	})
+
+	var data userData
+	assert.NoError(t, c.Do(context.Background(), Get, "/api/user/data", nil, &data))
+	assert.Equal(t, userData{Username: "admin", Forename: "Admin"}, data)
+
+	// An empty response leaves `out` unchanged
+	assert.NoError(t, c.Do(context.Background(), Put, "/api/user/data", userData{Forename: "Foo"}, &data))
+	assert.Equal(t, "Admin", data.Forename)
+
+	err = c.Do(context.Background(), Put, "/api/user/data", userData{}, nil)
+	assert.ErrorIs(t, err, ErrUnprocessableEntity)
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "forename is empty", apiErr.Response.Message)
+
+	assert.ErrorIs(t, c.Do(context.Background(), Get, "/api/does/not/exist", nil, nil), ErrNotFound)
+	assert.ErrorIs(t, c.Do(context.Background(), Get, "api/user/data", nil, nil), ErrInvalidURL)
+	assert.ErrorIs(t, c.Do(context.Background(), Get, "/api/user/data?foo=bar", nil, nil), ErrInvalidURL)
+}
+
+func TestStatusError(t *testing.T) {
+	for status, sentinel := range map[int]error{
+		400: ErrBadRequest,
+		401: ErrInvalidCredentials,
+		403: ErrPermissionDenied,
+		404: ErrNotFound,
+		409: ErrConflict,
+		422: ErrUnprocessableEntity,
+		500: ErrInternalServerError,
+		503: ErrServiceUnavailable,
+		418: ErrUnknownResponseCode,
+	} {
+		assert.Equal(t, sentinel, statusError(status), status)
+	}
+}
@@ -1,23 +1,130 @@
 package sdk
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
 
 var (
 	ErrNotInitialized            = errors.New("action failed: initialize connection first")
+	ErrConnectionClosed          = errors.New("action failed: the connection has been closed")
 	ErrInvalidURL                = errors.New("invalid url: the url could not be parsed")
+	ErrInvalidOption             = errors.New("invalid option: the connection could not be configured")
 	ErrInvalidFunctionAuthMethod = errors.New("the requested function cannot be used with the specified authentication mode")
 	ErrConnFailed                = errors.New("connection failed: request failed due to network issues")
+	ErrCertificatePinMismatch    = errors.New("connection failed: no certificate of the server matches the pinned fingerprints")
+	ErrCircuitOpen               = errors.New("request failed: the circuit breaker is open because smarthome is unavailable")
+	ErrCanceled                  = errors.New("request canceled: the context was canceled or its deadline was exceeded")
+	ErrNoServerAvailable         = errors.New("request failed: none of the smarthome servers is available")
 	ErrServiceUnavailable        = errors.New("request failed: smarthome is currently unavailable")
 	ErrInternalServerError       = errors.New("request failed: smarthome failed internally")
 	ErrInvalidCredentials        = errors.New("authentication failed: invalid credentials")
+	ErrCredentialsUnavailable    = errors.New("authentication failed: credentials could not be obtained")
 	ErrNoCookiesSent             = errors.New("login request did not respond with an expected cookie")
 	ErrAlreadyInitialized        = errors.New("cannot initialize: already initialized")
 	ErrUnauthorized              = errors.New("request failed: not authorized")
 	ErrReadResponseBody          = errors.New("could not read body from response")
+	ErrUnknownFields             = errors.New("could not read body from response: the response contains unknown fields")
 	ErrPermissionDenied          = errors.New("no permission to access this resource")
+	ErrBadRequest                = errors.New("request failed: the server rejected the request as malformed")
+	ErrNotFound                  = errors.New("request failed: the requested resource does not exist")
 	ErrInvalidSwitch             = errors.New("invalid switch id: no such switch exists")
 	ErrUnprocessableEntity       = errors.New("unprocessable entity: invalid or conflicting data")
 	ErrConflict                  = errors.New("conflict: modification of data would create data conflicts")
 	ErrUnsupportedVersion        = errors.New("this Smarthome version is not supported by the SDK")
+	ErrFeatureUnsupported        = errors.New("this feature is not supported by the connected Smarthome version")
 	ErrInvalidVersion            = errors.New("encountered unparsable SemVer version")
+	ErrUnknownResponseCode       = errors.New("request failed: unknown response code")
+	ErrSessionInvalid            = errors.New("cannot resume session: the session is invalid or has expired")
+	ErrNoSession                 = errors.New("no session has been stored")
 )
+
+// Is returned when a request is aborted because its context was canceled or its deadline was exceeded
+// Matches `ErrCanceled` as well as the underlying context error (`context.Canceled` or `context.DeadlineExceeded`)
+type canceledError struct {
+	cause error
+}
+
+func (e *canceledError) Error() string {
+	return "request canceled: " + e.cause.Error()
+}
+
+func (e *canceledError) Is(target error) bool {
+	return target == ErrCanceled
+}
+
+func (e *canceledError) Unwrap() error {
+	return e.cause
+}
+
+// Is returned when a request exceeds its own timeout, for example the timeout of a Homescript function or `WithDefaultTimeout`
+// Matches `ErrConnFailed`, but is neither counted as a failure of the server by the circuit breaker nor causes a failover
+type timeoutError struct{}
+
+func (e *timeoutError) Error() string {
+	return ErrConnFailed.Error() + ": the request exceeded its timeout"
+}
+
+func (e *timeoutError) Is(target error) bool {
+	return target == ErrConnFailed
+}
+
+// Used internally in order to check whether a request failed because it exceeded its own timeout
+func isTimeout(err error) bool {
+	var timeoutErr *timeoutError
+	return errors.As(err, &timeoutErr)
+}
+
+// Is returned when the Smarthome server responds with an unexpected or unsuccessful status code
+// Wraps the matching sentinel error, so that `errors.Is(err, ErrPermissionDenied)` continues to work
+// The response of the server is decoded into `Response` if the server sent a `GenericResponse`
+type APIError struct {
+	// The HTTP method of the failed request
+	Method string
+	// The path of the failed request, without the query which might contain credentials
+	Path string
+	// The HTTP status code which was returned by the server
+	StatusCode int
+	// The response which was sent by the server, empty if the body was not a `GenericResponse`
+	Response GenericResponse
+	// The sentinel error which describes the failure, for example `ErrPermissionDenied`
+	Err error
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode), e.Err)
+	if e.Response.Message != "" {
+		msg += fmt.Sprintf(" (server message: %s)", e.Response.Message)
+	}
+	if e.Response.Error != "" {
+		msg += fmt.Sprintf(" (server error: %s)", e.Response.Error)
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Used internally in order to create an `APIError` from an unsuccessful response
+// The response body is read and decoded into a `GenericResponse` if possible
+func newAPIError(res *http.Response, sentinel error) error {
+	apiErr := &APIError{
+		StatusCode: res.StatusCode,
+		Err:        sentinel,
+	}
+	if res.Request != nil {
+		apiErr.Method = res.Request.Method
+		apiErr.Path = res.Request.URL.Path
+	}
+	// Limit the size of the body in order to prevent huge error pages from being read into memory
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if err == nil {
+		// The body is optional, decoding errors are therefore ignored
+		_ = json.Unmarshal(body, &apiErr.Response)
+	}
+	return apiErr
+}
@@ -0,0 +1,61 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIError(t *testing.T) {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/power/set", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		assert.NoError(t, json.NewEncoder(w).Encode(GenericResponse{
+			Success: false,
+			Message: "permission denied",
+			Error:   "missing permission to use switch",
+			Time:    "now",
+		}))
+	})
+
+	r.HandleFunc("/api/switch/list/all", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodNone)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+
+	// Known status codes still match their sentinel error
+	err = c.SetPower("s1", true)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "POST", apiErr.Method)
+	assert.Equal(t, "/api/power/set", apiErr.Path)
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	assert.Equal(t, "missing permission to use switch", apiErr.Response.Error)
+	assert.Contains(t, err.Error(), "missing permission to use switch")
+
+	// Unknown status codes are reported as well, even without a body
+	_, err = c.GetAllSwitches()
+	assert.ErrorIs(t, err, ErrUnknownResponseCode)
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusTeapot, apiErr.StatusCode)
+	assert.Equal(t, GenericResponse{}, apiErr.Response)
+}
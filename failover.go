@@ -0,0 +1,216 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/Masterminds/semver"
+)
+
+// Adds standby Smarthome servers which are used if the primary server is unavailable
+// The servers are preferred in the order in which they are specified, after the URL passed to `NewConnection`
+// When connecting, every server is checked using `HealthCheck` and `Version` and the first available one is used
+// If a request fails with `ErrConnFailed` or `503 Service Unavailable`, the connection switches to the first available server
+// Requests which exceed their own timeout, like the timeout of a Homescript function, do not cause a failover
+// Cookie-authenticated connections log in again after switching, because sessions are not shared between servers
+// The failed request is repeated on the new server if it is safe to retry, see `RetryPolicy`
+// `SmarthomeVersion` keeps the version of the server which was used when connecting, capability checks use the active server
+func WithFailoverURLs(urls ...string) Option {
+	return func(o *options) error {
+		for _, rawURL := range urls {
+			u, err := parseBaseURL(rawURL)
+			if err != nil {
+				return fmt.Errorf("%w: invalid failover URL `%s`", ErrInvalidOption, rawURL)
+			}
+			o.failoverURLs = append(o.failoverURLs, u)
+		}
+		return nil
+	}
+}
+
+// Registers a function which is called whenever the connection switches to another server
+// The error is nil if the connection could log in to the new server or if no login was required
+func WithFailoverHook(hook func(from string, to string, err error)) Option {
+	return func(o *options) error {
+		o.failoverHook = hook
+		return nil
+	}
+}
+
+// Records which Smarthome server responded to the requests of a call
+// Is attached to a context using `WithServedBy`, safe for concurrent use by multiple goroutines
+type ServedBy struct {
+	mu  sync.Mutex
+	url string
+}
+
+// Returns the base URL of the server which responded to the latest request, empty if no response was received
+func (s *ServedBy) URL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.url
+}
+
+// Returns a context which records the server that responded to every request sent using it
+// Can be used in order to find out which server served a call if failover URLs are configured
+func WithServedBy(ctx context.Context, servedBy *ServedBy) context.Context {
+	return context.WithValue(ctx, servedByKey{}, servedBy)
+}
+
+// Used as the context key for `ServedBy`
+type servedByKey struct{}
+
+// Used internally in order to record the server which responded to a request
+func recordServedBy(ctx context.Context, base *url.URL) {
+	servedBy, ok := ctx.Value(servedByKey{}).(*ServedBy)
+	if !ok {
+		return
+	}
+	servedBy.mu.Lock()
+	servedBy.url = base.String()
+	servedBy.mu.Unlock()
+}
+
+// Returns the base URL of the server which currently receives every request
+func (c *Connection) ActiveURL() string {
+	return c.baseURL().String()
+}
+
+// Used internally in order to access the base URL of the active server
+func (c *Connection) baseURL() *url.URL {
+	return c.endpoints[c.activeIndex()]
+}
+
+// Used internally in order to access the index of the active server
+func (c *Connection) activeIndex() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.active
+}
+
+// Checks every server and switches to the first available one
+// Can be used in order to return to the primary server once it is available again
+// Does nothing if no failover URLs are configured
+func (c *Connection) SelectServer() error {
+	return c.SelectServerContext(context.Background())
+}
+
+// Same as `SelectServer`, but binds the requests to `ctx`: cancellation yields an error matching `ErrCanceled`
+/** Errors
+- nil
+- ErrNotInitialized
+- ErrConnectionClosed
+- ErrNoServerAvailable
+- Relogin errors
+*/
+func (c *Connection) SelectServerContext(ctx context.Context) error {
+	if err := c.checkReady(); err != nil {
+		return err
+	}
+	if len(c.endpoints) < 2 {
+		return nil
+	}
+	_, err := c.failover(ctx, c.activeIndex())
+	return err
+}
+
+// Used internally in order to check whether a failed request should cause a failover
+// Requests which exceeded their own timeout, for example a long-running Homescript, do not cause a failover
+func (c *Connection) shouldFailover(res *http.Response, err error) bool {
+	if len(c.endpoints) < 2 {
+		return false
+	}
+	return (errors.Is(err, ErrConnFailed) && !isTimeout(err)) || (err == nil && res.StatusCode == http.StatusServiceUnavailable)
+}
+
+// Used internally in order to switch to the first available server after a request to the server `failed` has failed
+// Returns true if a different server than `failed` is active afterwards
+// If another goroutine has already switched to another server, the servers are not probed again
+/** Errors
+- nil
+- ErrNoServerAvailable
+- ErrCanceled
+- Relogin errors
+*/
+func (c *Connection) failover(ctx context.Context, failed int) (bool, error) {
+	c.failoverMu.Lock()
+	switched, notify, err := c.switchServer(ctx, failed)
+	c.failoverMu.Unlock()
+	// The hook may send requests itself, which could fail over again
+	notify()
+	return switched, err
+}
+
+// Used internally by `failover` in order to probe the servers and to switch to the first available one, `failoverMu` must be held
+// Returns a function which resets the circuit breaker and notifies the logger and the failover hook, it must be called after `failoverMu` has been released
+func (c *Connection) switchServer(ctx context.Context, failed int) (bool, func(), error) {
+	if c.activeIndex() != failed {
+		return true, func() {}, nil
+	}
+	index, _, version, err := c.probeEndpoints(ctx)
+	if err != nil {
+		return false, func() {}, err
+	}
+	c.mu.Lock()
+	c.serverVersion = version
+	if index == failed {
+		c.mu.Unlock()
+		return false, func() {}, nil
+	}
+	c.active = index
+	c.mu.Unlock()
+
+	// Sessions are not shared between servers
+	var loginErr error
+	if c.usesCookieAuth() {
+		loginErr = c.relogin(ctx, c.cookie())
+	}
+	notify := func() {
+		// Failures of the previous server must not keep the circuit open
+		c.resetBreaker()
+		c.logFailover(ctx, c.endpoints[failed], c.endpoints[index], loginErr)
+		if c.failoverHook != nil {
+			c.failoverHook(c.endpoints[failed].String(), c.endpoints[index].String(), loginErr)
+		}
+	}
+	return loginErr == nil, notify, loginErr
+}
+
+// Used internally in order to find the first server which is healthy and runs a supported version
+// Returns the index of the server as well as its version
+/** Errors
+- nil
+- ErrNoServerAvailable
+- ErrCanceled
+*/
+func (c *Connection) probeEndpoints(ctx context.Context) (int, VersionResponse, *semver.Version, error) {
+	var lastErr error
+	for index, base := range c.endpoints {
+		version, parsed, err := c.probeEndpoint(ctx, base)
+		if err == nil {
+			return index, version, parsed, nil
+		}
+		if errors.Is(err, ErrCanceled) {
+			return 0, VersionResponse{}, nil, err
+		}
+		lastErr = err
+	}
+	return 0, VersionResponse{}, nil, fmt.Errorf("%w: %w", ErrNoServerAvailable, lastErr)
+}
+
+// Used internally in order to check whether a single server is healthy and runs a supported version
+// Partially degraded servers are considered to be available
+func (c *Connection) probeEndpoint(ctx context.Context, base *url.URL) (VersionResponse, *semver.Version, error) {
+	status, err := c.healthCheckAt(ctx, base)
+	if err != nil {
+		return VersionResponse{}, nil, err
+	}
+	if status != StatusHealthy && status != StatusPartiallyDegraded {
+		return VersionResponse{}, nil, ErrServiceUnavailable
+	}
+	return c.serverVersionAt(ctx, base)
+}
@@ -0,0 +1,257 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Creates a Smarthome server which issues its own session cookie and can be marked as unavailable
+func newFailoverTestServer(t *testing.T, session string, down *atomic.Bool, logins *atomic.Int32) *httptest.Server {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		logins.Add(1)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: session})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.HandleFunc("/api/switch/list/personal", func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != session {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("[]"))
+	})
+
+	return httptest.NewServer(r)
+}
+
+func TestFailover(t *testing.T) {
+	var primaryDown, standbyDown atomic.Bool
+	var primaryLogins, standbyLogins atomic.Int32
+	primary := newFailoverTestServer(t, "primary", &primaryDown, &primaryLogins)
+	defer primary.Close()
+	standby := newFailoverTestServer(t, "standby", &standbyDown, &standbyLogins)
+	defer standby.Close()
+
+	var switches [][2]string
+	c, err := NewConnection(primary.URL, AuthMethodCookiePassword,
+		WithFailoverURLs(standby.URL),
+		WithFailoverHook(func(from string, to string, err error) {
+			assert.NoError(t, err)
+			switches = append(switches, [2]string{from, to})
+		}),
+	)
+	assert.NoError(t, err)
+
+	// The primary server is preferred
+	assert.NoError(t, c.UserLogin("admin", "admin"))
+	assert.Equal(t, primary.URL, c.ActiveURL())
+
+	var servedBy ServedBy
+	ctx := WithServedBy(context.Background(), &servedBy)
+	_, err = c.GetPersonalSwitchesContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, primary.URL, servedBy.URL())
+
+	// The request is repeated on the standby server after logging in to it
+	primaryDown.Store(true)
+	_, err = c.GetPersonalSwitchesContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, standby.URL, servedBy.URL())
+	assert.Equal(t, standby.URL, c.ActiveURL())
+	assert.Equal(t, int32(1), standbyLogins.Load())
+	assert.Equal(t, [][2]string{{primary.URL, standby.URL}}, switches)
+
+	// The connection stays on the standby server until a server is selected explicitly
+	primaryDown.Store(false)
+	_, err = c.GetPersonalSwitchesContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, standby.URL, servedBy.URL())
+
+	assert.NoError(t, c.SelectServer())
+	assert.Equal(t, primary.URL, c.ActiveURL())
+	assert.Equal(t, int32(2), primaryLogins.Load())
+	_, err = c.GetPersonalSwitchesContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, primary.URL, servedBy.URL())
+
+	// If no server is available, the original error is returned
+	primaryDown.Store(true)
+	standbyDown.Store(true)
+	_, err = c.GetPersonalSwitches()
+	assert.ErrorIs(t, err, ErrServiceUnavailable)
+	assert.ErrorIs(t, c.SelectServer(), ErrNoServerAvailable)
+	assert.Equal(t, primary.URL, c.ActiveURL())
+}
+
+func TestFailoverConnect(t *testing.T) {
+	var primaryDown, standbyDown atomic.Bool
+	var logins atomic.Int32
+	primary := newFailoverTestServer(t, "primary", &primaryDown, &logins)
+	standby := newFailoverTestServer(t, "standby", &standbyDown, &logins)
+	defer standby.Close()
+	primary.Close()
+
+	c, err := NewConnection(primary.URL, AuthMethodNone, WithFailoverURLs(standby.URL))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+	assert.Equal(t, standby.URL, c.ActiveURL())
+	assert.Equal(t, primary.URL, c.SmarthomeURL.String())
+
+	standbyDown.Store(true)
+	c, err = NewConnection(primary.URL, AuthMethodNone, WithFailoverURLs(standby.URL))
+	assert.NoError(t, err)
+	err = c.Connect()
+	assert.ErrorIs(t, err, ErrNoServerAvailable)
+	assert.ErrorIs(t, err, ErrServiceUnavailable)
+
+	_, err = NewConnection(primary.URL, AuthMethodNone, WithFailoverURLs("not a url"))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+}
+
+func TestFailoverIgnoresTimeouts(t *testing.T) {
+	var standbyDown atomic.Bool
+	var logins atomic.Int32
+	standby := newFailoverTestServer(t, "standby", &standbyDown, &logins)
+	defer standby.Close()
+
+	var probes atomic.Int32
+	r := http.NewServeMux()
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+	})
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+	// A long-running Homescript, the server itself is available
+	r.HandleFunc("/api/homescript/run/live", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	primary := httptest.NewServer(r)
+	defer primary.Close()
+
+	c, err := NewConnection(primary.URL, AuthMethodNone, WithFailoverURLs(standby.URL))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+	probed := probes.Load()
+
+	_, err = c.RunHomescriptCode("sleep(1)", nil, 20*time.Millisecond)
+	assert.ErrorIs(t, err, ErrConnFailed)
+	assert.Equal(t, primary.URL, c.ActiveURL())
+	assert.Equal(t, probed, probes.Load())
+}
+
+func TestFailoverSessionExport(t *testing.T) {
+	var primaryDown, standbyDown atomic.Bool
+	var logins atomic.Int32
+	primary := newFailoverTestServer(t, "primary", &primaryDown, &logins)
+	defer primary.Close()
+	standby := newFailoverTestServer(t, "standby", &standbyDown, &logins)
+	defer standby.Close()
+
+	c, err := NewConnection(primary.URL, AuthMethodCookiePassword, WithFailoverURLs(standby.URL))
+	assert.NoError(t, err)
+	assert.NoError(t, c.UserLogin("admin", "admin"))
+	state, err := c.ExportSession()
+	assert.NoError(t, err)
+	assert.Equal(t, primary.URL, state.ServerURL)
+
+	// After switching, the session records the server which issued the cookie
+	primaryDown.Store(true)
+	_, err = c.GetPersonalSwitches()
+	assert.NoError(t, err)
+	state, err = c.ExportSession()
+	assert.NoError(t, err)
+	assert.Equal(t, primary.URL, state.BaseURL)
+	assert.Equal(t, standby.URL, state.ServerURL)
+	assert.Equal(t, "standby", state.Cookie.Value)
+
+	// The session can be resumed as long as the same server is selected
+	resumed, err := NewConnection(primary.URL, AuthMethodCookiePassword, WithFailoverURLs(standby.URL))
+	assert.NoError(t, err)
+	assert.NoError(t, resumed.ResumeSession(state, nil))
+	assert.Equal(t, standby.URL, resumed.ActiveURL())
+
+	// The cookie of the standby server is never sent to the primary server
+	primaryDown.Store(false)
+	resumed, err = NewConnection(primary.URL, AuthMethodCookiePassword, WithFailoverURLs(standby.URL))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, resumed.ResumeSession(state, nil), ErrSessionInvalid)
+}
+
+func TestFailoverHookReentrant(t *testing.T) {
+	var primaryDown, standbyDown atomic.Bool
+	var logins atomic.Int32
+	primary := newFailoverTestServer(t, "primary", &primaryDown, &logins)
+	defer primary.Close()
+	standby := newFailoverTestServer(t, "standby", &standbyDown, &logins)
+	defer standby.Close()
+
+	var c *Connection
+	hookErrs := make(chan error, 2)
+	c, err := NewConnection(primary.URL, AuthMethodCookiePassword,
+		// Only one read request may be in flight, the failed response must not keep the slot
+		WithLimit(EndpointClassRead, Limit{MaxInFlight: 1}),
+		WithFailoverURLs(standby.URL),
+		WithFailoverHook(func(from string, to string, err error) {
+			// The hook may use the connection, including selecting a server
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			hookErrs <- c.SelectServerContext(ctx)
+			_, err = c.GetPersonalSwitchesContext(ctx)
+			hookErrs <- err
+		}),
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, c.UserLogin("admin", "admin"))
+
+	primaryDown.Store(true)
+	done := make(chan error)
+	go func() {
+		_, err := c.GetPersonalSwitches()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the failover hook has deadlocked")
+	}
+	assert.NoError(t, <-hookErrs)
+	assert.NoError(t, <-hookErrs)
+	assert.Equal(t, standby.URL, c.ActiveURL())
+}
@@ -1,6 +1,6 @@
 module github.com/smarthome-go/sdk
 
-go 1.18
+go 1.21
 
 require (
 	github.com/Masterminds/semver v1.5.0
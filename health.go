@@ -1,9 +1,10 @@
 package sdk
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"io"
 	"net/http"
+	"net/url"
 )
 
 type HealthStatus uint
@@ -25,17 +26,34 @@ type VersionResponse struct {
 /** Errors
 - nil
 - ErrConnFailed
-- ErrReadResponseBody
+- ErrUnknownResponseCode
 */
 func (c *Connection) HealthCheck() (status HealthStatus, err error) {
-	u := c.SmarthomeURL
-	u.Path = "/health"
+	return c.HealthCheckContext(context.Background())
+}
+
+// Same as `HealthCheck`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) HealthCheckContext(ctx context.Context) (status HealthStatus, err error) {
+	return c.healthCheckAt(ctx, c.baseURL())
+}
+
+// Used internally in order to check the health of a specific Smarthome server, for example a failover candidate
+func (c *Connection) healthCheckAt(ctx context.Context, base *url.URL) (status HealthStatus, err error) {
+	u := endpointURLAt(base, "/health")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return StatusUnknown, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
 
 	// Check if the base URL is working and the server is reachable
-	res, err := http.Get(u.String())
+	res, err := c.roundTrip(EndpointHealthCheck, 1, req)
 	if err != nil {
-		return StatusUnknown, ErrConnFailed
+		return StatusUnknown, contextError(ctx, err)
 	}
+	defer res.Body.Close()
+	recordServedBy(ctx, base)
 	switch res.StatusCode {
 	case 200:
 		return StatusHealthy, nil
@@ -44,29 +62,60 @@ func (c *Connection) HealthCheck() (status HealthStatus, err error) {
 	case 503:
 		return StatusDegraded, nil
 	}
-	return StatusUnknown, fmt.Errorf("unknown response code: %s", res.Status)
+	return StatusUnknown, newAPIError(res, ErrUnknownResponseCode)
 }
 
 // Can be used to retrieve the current version of the Smarthome server
+// Fields which are unknown to the SDK are handled according to the connection's decode mode
 /** Errors
 - nil
 - ErrConnFailed
 - ErrReadResponseBody
+- UnknownFieldsError (only in strict decode mode)
+- ErrServiceUnavailable
+- ErrUnknownResponseCode
 */
 func (c *Connection) Version() (version VersionResponse, err error) {
-	u := c.SmarthomeURL
-	u.Path = "/api/version"
+	return c.VersionContext(context.Background())
+}
+
+// Same as `Version`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) VersionContext(ctx context.Context) (version VersionResponse, err error) {
+	return c.versionAt(ctx, c.baseURL())
+}
 
-	res, err := http.Get(u.String())
+// Used internally in order to retrieve the version of a specific Smarthome server, for example a failover candidate
+func (c *Connection) versionAt(ctx context.Context, base *url.URL) (version VersionResponse, err error) {
+	u := endpointURLAt(base, "/api/version")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return VersionResponse{}, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	res, err := c.roundTrip(EndpointVersion, 1, req)
 	if err != nil {
-		return VersionResponse{}, ErrConnFailed
+		return VersionResponse{}, contextError(ctx, err)
 	}
+	defer res.Body.Close()
+	recordServedBy(ctx, base)
 
-	decoder := json.NewDecoder(res.Body)
-	decoder.DisallowUnknownFields()
-	if err := decoder.Decode(&version); err != nil {
+	switch res.StatusCode {
+	case 200:
+	case 503:
+		return VersionResponse{}, newAPIError(res, ErrServiceUnavailable)
+	default:
+		return VersionResponse{}, newAPIError(res, ErrUnknownResponseCode)
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
 		return VersionResponse{}, ErrReadResponseBody
 	}
+	if err := c.decode(EndpointVersion, resBody, &version); err != nil {
+		return VersionResponse{}, err
+	}
 
 	return version, nil
 }
@@ -1,10 +1,9 @@
 package sdk
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"time"
 )
@@ -90,16 +89,22 @@ type HomescriptRequest struct {
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
 - ErrPermissionDenied
 - PrepareRequest errors
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) RunHomescriptCode(code string, args map[string]string, timeout time.Duration) (response HomescriptResponse, err error) {
-	if !c.ready {
-		return HomescriptResponse{}, ErrNotInitialized
+	return c.RunHomescriptCodeContext(context.Background(), code, args, timeout)
+}
+
+// Same as `RunHomescriptCode`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) RunHomescriptCodeContext(ctx context.Context, code string, args map[string]string, timeout time.Duration) (response HomescriptResponse, err error) {
+	if err := c.checkReady(); err != nil {
+		return HomescriptResponse{}, err
 	}
 	argsTemp := make([]HomescriptRunArgRequest, 0)
 	for key, value := range args {
@@ -108,20 +113,13 @@ func (c *Connection) RunHomescriptCode(code string, args map[string]string, time
 			Value: value,
 		})
 	}
-	req, err := c.prepareRequest("/api/homescript/run/live", Post, RunHomescriptStringRequest{
+	res, err := c.sendTimeout(ctx, "RunHomescriptCode", timeout, "/api/homescript/run/live", Post, RunHomescriptStringRequest{
 		Code: code,
 		Args: argsTemp,
 	})
 	if err != nil {
 		return HomescriptResponse{}, err
 	}
-	client := &http.Client{
-		Timeout: timeout,
-	}
-	res, err := client.Do(req)
-	if err != nil {
-		return HomescriptResponse{}, ErrConnFailed
-	}
 	defer res.Body.Close()
 
 	switch res.StatusCode {
@@ -132,16 +130,16 @@ func (c *Connection) RunHomescriptCode(code string, args map[string]string, time
 			return HomescriptResponse{}, ErrReadResponseBody
 		}
 		var parsedBody HomescriptResponse
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return HomescriptResponse{}, ErrReadResponseBody
+		if err := c.decode("RunHomescriptCode", resBody, &parsedBody); err != nil {
+			return HomescriptResponse{}, err
 		}
 		return parsedBody, nil
 	case 401:
-		return HomescriptResponse{}, ErrInvalidCredentials
+		return HomescriptResponse{}, newAPIError(res, ErrInvalidCredentials)
 	case 403:
-		return HomescriptResponse{}, ErrPermissionDenied
+		return HomescriptResponse{}, newAPIError(res, ErrPermissionDenied)
 	}
-	return HomescriptResponse{}, fmt.Errorf("unknown response code: %s", res.Status)
+	return HomescriptResponse{}, newAPIError(res, ErrUnknownResponseCode)
 }
 
 // Runs Homescript by id on the Smarthome-server
@@ -152,16 +150,22 @@ func (c *Connection) RunHomescriptCode(code string, args map[string]string, time
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
 - ErrPermissionDenied
 - PrepareRequest errors
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) RunHomescriptById(id string, args map[string]string, timeout time.Duration) (response HomescriptResponse, err error) {
-	if !c.ready {
-		return HomescriptResponse{}, ErrNotInitialized
+	return c.RunHomescriptByIdContext(context.Background(), id, args, timeout)
+}
+
+// Same as `RunHomescriptById`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) RunHomescriptByIdContext(ctx context.Context, id string, args map[string]string, timeout time.Duration) (response HomescriptResponse, err error) {
+	if err := c.checkReady(); err != nil {
+		return HomescriptResponse{}, err
 	}
 	argsTemp := make([]HomescriptRunArgRequest, 0)
 	for key, value := range args {
@@ -170,20 +174,13 @@ func (c *Connection) RunHomescriptById(id string, args map[string]string, timeou
 			Value: value,
 		})
 	}
-	req, err := c.prepareRequest("/api/homescript/run", Post, RunHomescriptByIdRequest{
+	res, err := c.sendTimeout(ctx, "RunHomescriptById", timeout, "/api/homescript/run", Post, RunHomescriptByIdRequest{
 		Id:   id,
 		Args: argsTemp,
 	})
 	if err != nil {
 		return HomescriptResponse{}, err
 	}
-	client := &http.Client{
-		Timeout: timeout,
-	}
-	res, err := client.Do(req)
-	if err != nil {
-		return HomescriptResponse{}, ErrConnFailed
-	}
 	defer res.Body.Close()
 
 	switch res.StatusCode {
@@ -194,18 +191,18 @@ func (c *Connection) RunHomescriptById(id string, args map[string]string, timeou
 			return HomescriptResponse{}, ErrReadResponseBody
 		}
 		var parsedBody HomescriptResponse
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return HomescriptResponse{}, ErrReadResponseBody
+		if err := c.decode("RunHomescriptById", resBody, &parsedBody); err != nil {
+			return HomescriptResponse{}, err
 		}
 		return parsedBody, nil
 	case 401:
-		return HomescriptResponse{}, ErrInvalidCredentials
+		return HomescriptResponse{}, newAPIError(res, ErrInvalidCredentials)
 	case 403:
-		return HomescriptResponse{}, ErrPermissionDenied
+		return HomescriptResponse{}, newAPIError(res, ErrPermissionDenied)
 	case 422:
-		return HomescriptResponse{}, ErrUnprocessableEntity
+		return HomescriptResponse{}, newAPIError(res, ErrUnprocessableEntity)
 	}
-	return HomescriptResponse{}, fmt.Errorf("unknown response code: %s", res.Status)
+	return HomescriptResponse{}, newAPIError(res, ErrUnknownResponseCode)
 }
 
 // Lints a string of Homescript code on the Smarthome-server
@@ -214,16 +211,22 @@ func (c *Connection) RunHomescriptById(id string, args map[string]string, timeou
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
 - ErrPermissionDenied
 - PrepareRequest errors
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) LintHomescriptCode(code string, args map[string]string, timeout time.Duration) (response HomescriptResponse, err error) {
-	if !c.ready {
-		return HomescriptResponse{}, ErrNotInitialized
+	return c.LintHomescriptCodeContext(context.Background(), code, args, timeout)
+}
+
+// Same as `LintHomescriptCode`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) LintHomescriptCodeContext(ctx context.Context, code string, args map[string]string, timeout time.Duration) (response HomescriptResponse, err error) {
+	if err := c.checkReady(); err != nil {
+		return HomescriptResponse{}, err
 	}
 	argsTemp := make([]HomescriptRunArgRequest, 0)
 	for key, value := range args {
@@ -232,20 +235,13 @@ func (c *Connection) LintHomescriptCode(code string, args map[string]string, tim
 			Value: value,
 		})
 	}
-	req, err := c.prepareRequest("/api/homescript/lint/live", Post, RunHomescriptStringRequest{
+	res, err := c.sendTimeout(ctx, "LintHomescriptCode", timeout, "/api/homescript/lint/live", Post, RunHomescriptStringRequest{
 		Code: code,
 		Args: argsTemp,
 	})
 	if err != nil {
 		return HomescriptResponse{}, err
 	}
-	client := &http.Client{
-		Timeout: timeout,
-	}
-	res, err := client.Do(req)
-	if err != nil {
-		return HomescriptResponse{}, ErrConnFailed
-	}
 	defer res.Body.Close()
 
 	switch res.StatusCode {
@@ -256,16 +252,16 @@ func (c *Connection) LintHomescriptCode(code string, args map[string]string, tim
 			return HomescriptResponse{}, ErrReadResponseBody
 		}
 		var parsedBody HomescriptResponse
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return HomescriptResponse{}, ErrReadResponseBody
+		if err := c.decode("LintHomescriptCode", resBody, &parsedBody); err != nil {
+			return HomescriptResponse{}, err
 		}
 		return parsedBody, nil
 	case 401:
-		return HomescriptResponse{}, ErrInvalidCredentials
+		return HomescriptResponse{}, newAPIError(res, ErrInvalidCredentials)
 	case 403:
-		return HomescriptResponse{}, ErrPermissionDenied
+		return HomescriptResponse{}, newAPIError(res, ErrPermissionDenied)
 	}
-	return HomescriptResponse{}, fmt.Errorf("unknown response code: %s", res.Status)
+	return HomescriptResponse{}, newAPIError(res, ErrUnknownResponseCode)
 }
 
 // Lints a Homescript by id
@@ -274,16 +270,22 @@ func (c *Connection) LintHomescriptCode(code string, args map[string]string, tim
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
 - ErrPermissionDenied
 - PrepareRequest errors
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) LintHomescriptById(id string, args map[string]string, timeout time.Duration) (response HomescriptResponse, err error) {
-	if !c.ready {
-		return HomescriptResponse{}, ErrNotInitialized
+	return c.LintHomescriptByIdContext(context.Background(), id, args, timeout)
+}
+
+// Same as `LintHomescriptById`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) LintHomescriptByIdContext(ctx context.Context, id string, args map[string]string, timeout time.Duration) (response HomescriptResponse, err error) {
+	if err := c.checkReady(); err != nil {
+		return HomescriptResponse{}, err
 	}
 	argsTemp := make([]HomescriptRunArgRequest, 0)
 	for key, value := range args {
@@ -292,20 +294,13 @@ func (c *Connection) LintHomescriptById(id string, args map[string]string, timeo
 			Value: value,
 		})
 	}
-	req, err := c.prepareRequest("/api/homescript/lint", Post, RunHomescriptByIdRequest{
+	res, err := c.sendTimeout(ctx, "LintHomescriptById", timeout, "/api/homescript/lint", Post, RunHomescriptByIdRequest{
 		Id:   id,
 		Args: argsTemp,
 	})
 	if err != nil {
 		return HomescriptResponse{}, err
 	}
-	client := &http.Client{
-		Timeout: timeout,
-	}
-	res, err := client.Do(req)
-	if err != nil {
-		return HomescriptResponse{}, ErrConnFailed
-	}
 	defer res.Body.Close()
 
 	switch res.StatusCode {
@@ -316,18 +311,18 @@ func (c *Connection) LintHomescriptById(id string, args map[string]string, timeo
 			return HomescriptResponse{}, ErrReadResponseBody
 		}
 		var parsedBody HomescriptResponse
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return HomescriptResponse{}, ErrReadResponseBody
+		if err := c.decode("LintHomescriptById", resBody, &parsedBody); err != nil {
+			return HomescriptResponse{}, err
 		}
 		return parsedBody, nil
 	case 401:
-		return HomescriptResponse{}, ErrInvalidCredentials
+		return HomescriptResponse{}, newAPIError(res, ErrInvalidCredentials)
 	case 403:
-		return HomescriptResponse{}, ErrPermissionDenied
+		return HomescriptResponse{}, newAPIError(res, ErrPermissionDenied)
 	case 422:
-		return HomescriptResponse{}, ErrUnprocessableEntity
+		return HomescriptResponse{}, newAPIError(res, ErrUnprocessableEntity)
 	}
-	return HomescriptResponse{}, fmt.Errorf("unknown response code: %s", res.Status)
+	return HomescriptResponse{}, newAPIError(res, ErrUnknownResponseCode)
 
 }
 
@@ -335,84 +330,87 @@ func (c *Connection) LintHomescriptById(id string, args map[string]string, timeo
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
 - ErrPermissionDenied
 - PrepareRequest errors
 - ErrUnprocessableEntity (conflicting id / invalid data)
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) CreateHomescript(data HomescriptRequest) error {
-	if !c.ready {
-		return ErrNotInitialized
-	}
-	req, err := c.prepareRequest("/api/homescript/add", Post, data)
-	if err != nil {
+	return c.CreateHomescriptContext(context.Background(), data)
+}
+
+// Same as `CreateHomescript`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) CreateHomescriptContext(ctx context.Context, data HomescriptRequest) error {
+	if err := c.checkReady(); err != nil {
 		return err
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := c.send(ctx, "CreateHomescript", "/api/homescript/add", Post, data)
 	if err != nil {
-		return ErrConnFailed
+		return err
 	}
 	defer res.Body.Close()
 	switch res.StatusCode {
 	case 200:
 		return nil
 	case 401:
-		return ErrInvalidCredentials
+		return newAPIError(res, ErrInvalidCredentials)
 	case 422:
-		return ErrUnprocessableEntity
+		return newAPIError(res, ErrUnprocessableEntity)
 	case 403:
-		return ErrPermissionDenied
+		return newAPIError(res, ErrPermissionDenied)
 	}
-	return fmt.Errorf("unknown response code: %s", res.Status)
+	return newAPIError(res, ErrUnknownResponseCode)
 }
 
 // Modifies an existing Homescript which is owned by the current user
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
 - ErrPermissionDenied
 - PrepareRequest errors
 - ErrUnprocessableEntity (invalid id / in valid data)
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) ModifyHomescript(data HomescriptRequest) error {
-	if !c.ready {
-		return ErrNotInitialized
-	}
-	req, err := c.prepareRequest("/api/homescript/modify", Put, data)
-	if err != nil {
+	return c.ModifyHomescriptContext(context.Background(), data)
+}
+
+// Same as `ModifyHomescript`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) ModifyHomescriptContext(ctx context.Context, data HomescriptRequest) error {
+	if err := c.checkReady(); err != nil {
 		return err
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := c.send(ctx, "ModifyHomescript", "/api/homescript/modify", Put, data)
 	if err != nil {
-		return ErrConnFailed
+		return err
 	}
 	defer res.Body.Close()
 	switch res.StatusCode {
 	case 200:
 		return nil
 	case 401:
-		return ErrInvalidCredentials
+		return newAPIError(res, ErrInvalidCredentials)
 	case 422:
-		return ErrUnprocessableEntity
+		return newAPIError(res, ErrUnprocessableEntity)
 	case 403:
-		return ErrPermissionDenied
+		return newAPIError(res, ErrPermissionDenied)
 	}
-	return fmt.Errorf("unknown response code: %s", res.Status)
+	return newAPIError(res, ErrUnknownResponseCode)
 }
 
 // Deletes an existing Homescript which is owned by the current user
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
@@ -420,63 +418,64 @@ func (c *Connection) ModifyHomescript(data HomescriptRequest) error {
 - PrepareRequest errors
 - ErrUnprocessableEntity (invalid id)
 - ErrConflict (dependent automations)
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) DeleteHomescript(id string) error {
-	if !c.ready {
-		return ErrNotInitialized
+	return c.DeleteHomescriptContext(context.Background(), id)
+}
+
+// Same as `DeleteHomescript`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) DeleteHomescriptContext(ctx context.Context, id string) error {
+	if err := c.checkReady(); err != nil {
+		return err
 	}
-	req, err := c.prepareRequest("/api/homescript/delete", Delete, struct {
+	res, err := c.send(ctx, "DeleteHomescript", "/api/homescript/delete", Delete, struct {
 		Id string `json:"id"`
 	}{id})
 	if err != nil {
 		return err
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return ErrConnFailed
-	}
 	defer res.Body.Close()
 	switch res.StatusCode {
 	case 200:
 		return nil
 	case 401:
-		return ErrInvalidCredentials
+		return newAPIError(res, ErrInvalidCredentials)
 	case 409:
-		return ErrConflict
+		return newAPIError(res, ErrConflict)
 	case 422:
-		return ErrUnprocessableEntity
+		return newAPIError(res, ErrUnprocessableEntity)
 	case 403:
-		return ErrPermissionDenied
+		return newAPIError(res, ErrPermissionDenied)
 	}
-	return fmt.Errorf("unknown response code: %s", res.Status)
+	return newAPIError(res, ErrUnknownResponseCode)
 }
 
 // Returns the metadata of a given homescript which is owned by the current user
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
 - ErrPermissionDenied
 - PrepareRequest errors
 - ErrUnprocessableEntity (invalid id)
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) GetHomescript(id string) (Homescript, error) {
-	if !c.ready {
-		return Homescript{}, ErrNotInitialized
-	}
-	req, err := c.prepareRequest(fmt.Sprintf("/api/homescript/get/%s", url.PathEscape(id)), Get, nil)
-	if err != nil {
+	return c.GetHomescriptContext(context.Background(), id)
+}
+
+// Same as `GetHomescript`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) GetHomescriptContext(ctx context.Context, id string) (Homescript, error) {
+	if err := c.checkReady(); err != nil {
 		return Homescript{}, err
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := c.send(ctx, "GetHomescript", fmt.Sprintf("/api/homescript/get/%s", url.PathEscape(id)), Get, nil)
 	if err != nil {
-		return Homescript{}, ErrConnFailed
+		return Homescript{}, err
 	}
 	defer res.Body.Close()
 	switch res.StatusCode {
@@ -486,43 +485,44 @@ func (c *Connection) GetHomescript(id string) (Homescript, error) {
 			return Homescript{}, ErrReadResponseBody
 		}
 		var parsedBody Homescript
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return Homescript{}, ErrReadResponseBody
+		if err := c.decode("GetHomescript", resBody, &parsedBody); err != nil {
+			return Homescript{}, err
 		}
 		return parsedBody, nil
 	case 401:
-		return Homescript{}, ErrInvalidCredentials
+		return Homescript{}, newAPIError(res, ErrInvalidCredentials)
 	case 422:
-		return Homescript{}, ErrUnprocessableEntity
+		return Homescript{}, newAPIError(res, ErrUnprocessableEntity)
 	case 403:
-		return Homescript{}, ErrPermissionDenied
+		return Homescript{}, newAPIError(res, ErrPermissionDenied)
 	}
-	return Homescript{}, fmt.Errorf("unknown response code: %s", res.Status)
+	return Homescript{}, newAPIError(res, ErrUnknownResponseCode)
 }
 
 // Returns a slice of Homescripts
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
 - ErrPermissionDenied
 - PrepareRequest errors
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) ListHomescript() ([]Homescript, error) {
-	if !c.ready {
-		return nil, ErrNotInitialized
-	}
-	req, err := c.prepareRequest("/api/homescript/list/personal", Get, nil)
-	if err != nil {
+	return c.ListHomescriptContext(context.Background())
+}
+
+// Same as `ListHomescript`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) ListHomescriptContext(ctx context.Context) ([]Homescript, error) {
+	if err := c.checkReady(); err != nil {
 		return nil, err
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := c.send(ctx, "ListHomescript", "/api/homescript/list/personal", Get, nil)
 	if err != nil {
-		return nil, ErrConnFailed
+		return nil, err
 	}
 	defer res.Body.Close()
 	switch res.StatusCode {
@@ -532,16 +532,16 @@ func (c *Connection) ListHomescript() ([]Homescript, error) {
 			return nil, ErrReadResponseBody
 		}
 		var parsedBody []Homescript
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return nil, ErrReadResponseBody
+		if err := c.decode("ListHomescript", resBody, &parsedBody); err != nil {
+			return nil, err
 		}
 		return parsedBody, nil
 	case 401:
-		return nil, ErrInvalidCredentials
+		return nil, newAPIError(res, ErrInvalidCredentials)
 	case 422:
-		return nil, ErrUnprocessableEntity
+		return nil, newAPIError(res, ErrUnprocessableEntity)
 	case 403:
-		return nil, ErrPermissionDenied
+		return nil, newAPIError(res, ErrPermissionDenied)
 	}
-	return nil, fmt.Errorf("unknown response code: %s", res.Status)
+	return nil, newAPIError(res, ErrUnknownResponseCode)
 }
@@ -1,10 +1,9 @@
 package sdk
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 )
 
@@ -63,25 +62,26 @@ type AddedHomescriptArgResponse struct {
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
 - ErrPermissionDenied
 - PrepareRequest errors
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) ListHomescriptArgsOfHmsId(homescriptId string) ([]HomescriptArg, error) {
-	if !c.ready {
-		return nil, ErrNotInitialized
-	}
-	req, err := c.prepareRequest(fmt.Sprintf("/api/homescript/arg/list/of/%s", url.PathEscape(homescriptId)), Get, nil)
-	if err != nil {
+	return c.ListHomescriptArgsOfHmsIdContext(context.Background(), homescriptId)
+}
+
+// Same as `ListHomescriptArgsOfHmsId`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) ListHomescriptArgsOfHmsIdContext(ctx context.Context, homescriptId string) ([]HomescriptArg, error) {
+	if err := c.checkReady(); err != nil {
 		return nil, err
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := c.send(ctx, "ListHomescriptArgsOfHmsId", fmt.Sprintf("/api/homescript/arg/list/of/%s", url.PathEscape(homescriptId)), Get, nil)
 	if err != nil {
-		return nil, ErrConnFailed
+		return nil, err
 	}
 	defer res.Body.Close()
 	switch res.StatusCode {
@@ -91,43 +91,44 @@ func (c *Connection) ListHomescriptArgsOfHmsId(homescriptId string) ([]Homescrip
 			return nil, ErrReadResponseBody
 		}
 		var parsedBody []HomescriptArg
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return nil, ErrReadResponseBody
+		if err := c.decode("ListHomescriptArgsOfHmsId", resBody, &parsedBody); err != nil {
+			return nil, err
 		}
 		return parsedBody, nil
 	case 401:
-		return nil, ErrInvalidCredentials
+		return nil, newAPIError(res, ErrInvalidCredentials)
 	case 422:
-		return nil, ErrUnprocessableEntity
+		return nil, newAPIError(res, ErrUnprocessableEntity)
 	case 403:
-		return nil, ErrPermissionDenied
+		return nil, newAPIError(res, ErrPermissionDenied)
 	}
-	return nil, fmt.Errorf("unknown response code: %s", res.Status)
+	return nil, newAPIError(res, ErrUnknownResponseCode)
 }
 
 // Returns a slice of Homescripts with their arguments
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
 - ErrReadResponseBody
 - ErrInvalidCredentials
 - ErrPermissionDenied
 - PrepareRequest errors
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) ListHomescriptWithArgs() ([]HomescriptWithArguments, error) {
-	if !c.ready {
-		return nil, ErrNotInitialized
-	}
-	req, err := c.prepareRequest("/api/homescript/list/personal/complete", Get, nil)
-	if err != nil {
+	return c.ListHomescriptWithArgsContext(context.Background())
+}
+
+// Same as `ListHomescriptWithArgs`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) ListHomescriptWithArgsContext(ctx context.Context) ([]HomescriptWithArguments, error) {
+	if err := c.checkReady(); err != nil {
 		return nil, err
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := c.send(ctx, "ListHomescriptWithArgs", "/api/homescript/list/personal/complete", Get, nil)
 	if err != nil {
-		return nil, ErrConnFailed
+		return nil, err
 	}
 	defer res.Body.Close()
 	switch res.StatusCode {
@@ -137,16 +138,16 @@ func (c *Connection) ListHomescriptWithArgs() ([]HomescriptWithArguments, error)
 			return nil, ErrReadResponseBody
 		}
 		var parsedBody []HomescriptWithArguments
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return nil, ErrReadResponseBody
+		if err := c.decode("ListHomescriptWithArgs", resBody, &parsedBody); err != nil {
+			return nil, err
 		}
 		return parsedBody, nil
 	case 401:
-		return nil, ErrInvalidCredentials
+		return nil, newAPIError(res, ErrInvalidCredentials)
 	case 422:
-		return nil, ErrUnprocessableEntity
+		return nil, newAPIError(res, ErrUnprocessableEntity)
 	case 403:
-		return nil, ErrPermissionDenied
+		return nil, newAPIError(res, ErrPermissionDenied)
 	}
-	return nil, fmt.Errorf("unknown response code: %s", res.Status)
+	return nil, newAPIError(res, ErrUnknownResponseCode)
 }
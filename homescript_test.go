@@ -28,6 +28,14 @@ func TestHomescript(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	})
 
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
 	r.HandleFunc("/api/homescript/run/live", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		assert.NoError(t, json.NewEncoder(w).Encode(HomescriptResponse{
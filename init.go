@@ -2,6 +2,7 @@ package sdk
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -20,134 +21,204 @@ type tokenLoginResponse struct {
 
 // Creates a new connection
 // First argument specifies the base URL of the target Smarthome-server
+// The base URL may contain a path prefix, for example `https://example.com/smarthome/` if Smarthome is served behind a reverse proxy
+// Query parameters of the base URL are sent with every request
 // Second argument specifies how to handle authentication
+// Further arguments are optional and can be used to configure the connection, for example using `WithHTTPClient`
 func NewConnection(
 	smarthomeURL string,
 	authMethod AuthMethod,
+	opts ...Option,
 ) (*Connection, error) {
-	u, err := url.Parse(smarthomeURL)
+	u, err := parseBaseURL(smarthomeURL)
 	if err != nil {
-		return nil, ErrInvalidURL
+		return nil, err
+	}
+	// Apply the options on top of the defaults
+	o := defaultOptions()
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	client, err := o.buildClient()
+	if err != nil {
+		return nil, err
 	}
 	// Create and return a client
 	return &Connection{
-		SmarthomeURL:  u,
-		authMethod:    authMethod,
-		sessionCookie: &http.Cookie{},
+		SmarthomeURL:         u,
+		authMethod:           authMethod,
+		sessionCookie:        &http.Cookie{},
+		client:               client,
+		userAgent:            o.userAgent,
+		reloginHook:          o.reloginHook,
+		retryPolicy:          o.retryPolicy,
+		logger:               o.logger,
+		sessionStore:         o.sessionStore,
+		decodeMode:           o.decodeMode,
+		unknownFieldsHandler: o.unknownFieldsHandler,
+		limiters:             newLimiters(o.limits),
+		breaker:              o.newBreaker(),
+		endpoints:            append([]*url.URL{u}, o.failoverURLs...),
+		failoverHook:         o.failoverHook,
+		done:                 make(chan struct{}),
 	}, nil
 }
 
+// Used internally in order to parse and validate a base URL
+/** Errors
+- nil
+- ErrInvalidURL
+*/
+func parseBaseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	// Fragments are never sent to the server
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u, nil
+}
+
 // Can be used to connect when the authentication method is set to `None`
 func (c *Connection) Connect() error {
+	return c.ConnectContext(context.Background())
+}
+
+// Same as `Connect`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) ConnectContext(ctx context.Context) error {
 	if c.authMethod != AuthMethodNone {
 		return ErrInvalidFunctionAuthMethod
 	}
 	// Call the helper function
-	return c.connectHelper()
+	return c.connectHelper(ctx)
 }
 
 // Can be used to connect when the authentication method is set to `Password-XXX`
 func (c *Connection) UserLogin(username string, password string) error {
-	if c.authMethod != AuthMethodQueryPassword && c.authMethod != AuthMethodCookiePassword {
+	return c.UserLoginContext(context.Background(), username, password)
+}
+
+// Same as `UserLogin`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) UserLoginContext(ctx context.Context, username string, password string) error {
+	if !c.usesPasswordAuth() {
 		return ErrInvalidFunctionAuthMethod
 	}
 	// Set the internal credentials using the parameters
+	c.mu.Lock()
 	c.credStore.Username = username
 	c.credStore.Password = password
+	c.credProvider = nil
+	c.mu.Unlock()
 	// Call the helper function
-	return c.connectHelper()
+	return c.connectHelper(ctx)
 }
 
 // Can be used to connect when the authentication method is set to `Token-XXX`
 func (c *Connection) TokenLogin(token string) error {
-	if c.authMethod != AuthMethodQueryToken && c.authMethod != AuthMethodCookieToken {
+	return c.TokenLoginContext(context.Background(), token)
+}
+
+// Same as `TokenLogin`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) TokenLoginContext(ctx context.Context, token string) error {
+	if !c.usesTokenAuth() {
 		return ErrInvalidFunctionAuthMethod
 	}
 	// Set the internal token to the parameter
+	c.mu.Lock()
 	c.credStore.Token = token
+	c.credProvider = nil
+	c.mu.Unlock()
 	// Call the helper function
-	return c.connectHelper()
+	return c.connectHelper(ctx)
 }
 
-// If the authentication mode is set to `AuthMethodNone`, both arguments can be set to nil
-// Otherwise, username and password are required to login
-func (c *Connection) connectHelper() error {
-
-	// Retrieve the server's version
-	version, err := c.Version()
-	if err != nil {
-		return err
-	}
-
-	// Set the version in the connection
-	// Is already set here so it can be used in error messages as `c.SmarthomeVersion`
-	c.SmarthomeVersion = version.Version
-	c.SmarthomeGoVersion = version.GoVersion
+// Can be used to connect using every authentication method except `None`
+// The provider is consulted on every login and, for URL-query and header authentication, on every request
+// This allows credentials to be rotated, for example by using a `FileCredentialProvider` for a mounted Kubernetes secret
+func (c *Connection) ProviderLogin(provider CredentialProvider) error {
+	return c.ProviderLoginContext(context.Background(), provider)
+}
 
-	// Check Smarthome version compatibility
-	supportedV, err := semver.NewConstraint(fmt.Sprintf("^%s", MinSmarthomeVersion))
-	if err != nil {
-		// This must not happen (tests)
-		// If this happens, the best thing is to abort the connection
-		return ErrInvalidVersion
+// Same as `ProviderLogin`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) ProviderLoginContext(ctx context.Context, provider CredentialProvider) error {
+	if c.authMethod == AuthMethodNone {
+		return ErrInvalidFunctionAuthMethod
 	}
+	c.mu.Lock()
+	c.credProvider = provider
+	c.mu.Unlock()
+	// Call the helper function
+	return c.connectHelper(ctx)
+}
 
-	currentV, err := semver.NewVersion(version.Version)
-	if err != nil {
-		// This must also not happen
-		// If this happens, the best thing is to abort the connection
-		return ErrInvalidVersion
+// If the authentication mode is set to `AuthMethodNone`, both arguments can be set to nil
+// Otherwise, username and password are required to login
+func (c *Connection) connectHelper(ctx context.Context) error {
+	if c.isClosed() {
+		return ErrConnectionClosed
 	}
-
-	// Perform the version comparison
-	if !supportedV.Check(currentV) {
-		// Would not be supported
-		return ErrUnsupportedVersion
+	if err := c.checkServerVersion(ctx); err != nil {
+		return err
 	}
 
 	switch c.authMethod {
 	// If the connection does not use authentication, it can be marked as ready
 	case AuthMethodNone:
+		c.mu.Lock()
 		c.ready = true
+		c.mu.Unlock()
 		return nil
 
-	// If the authentication mode is set to `AuthMethodQueryToken`, validate the token and mark the connection as ready
-	case AuthMethodQueryToken:
-		_, tokenData, err := c.doLogin()
+	// If the authentication mode is set to `AuthMethodQueryToken` or `AuthMethodHeaderToken`, validate the token and mark the connection as ready
+	case AuthMethodQueryToken, AuthMethodHeaderToken:
+		_, tokenData, err := c.doLogin(ctx)
 		if err != nil {
 			return err
 		}
+		c.mu.Lock()
 		c.tokenClientName = tokenData.TokenLabel
 		c.credStore.Username = tokenData.Username
 		c.ready = true
+		c.mu.Unlock()
 		return nil
-	// If the authentication mode is set to `AuthMethodQueryPassword`, validate the user's credentials and mark the connection as ready
-	case AuthMethodQueryPassword:
-		_, _, err := c.doLogin()
+	// If the authentication mode is set to `AuthMethodQueryPassword` or `AuthMethodHeaderPassword`, validate the user's credentials and mark the connection as ready
+	case AuthMethodQueryPassword, AuthMethodHeaderPassword:
+		_, _, err := c.doLogin(ctx)
 		if err != nil {
 			return err
 		}
+		c.mu.Lock()
 		c.ready = true
+		c.mu.Unlock()
 		return nil
 	// If the authentication mode is set to `AuthMethodCookieToken`, use the token to obtain a session cookie
 	case AuthMethodCookieToken:
-		cookie, tokenData, err := c.doLogin()
+		cookie, tokenData, err := c.doLogin(ctx)
 		if err != nil {
 			return err
 		}
+		c.mu.Lock()
 		c.tokenClientName = tokenData.TokenLabel
 		c.credStore.Username = tokenData.Username
 		c.sessionCookie = cookie
 		c.ready = true
+		c.mu.Unlock()
+		c.saveSession(ctx)
 		return nil
 	// If the authentication mode is set to `AuthMethodCookiePassword`, use the user's credentials to obtain a session cookie
 	case AuthMethodCookiePassword:
-		cookie, _, err := c.doLogin()
+		cookie, _, err := c.doLogin(ctx)
 		if err != nil {
 			return err
 		}
+		c.mu.Lock()
 		c.sessionCookie = cookie
 		c.ready = true
+		c.mu.Unlock()
+		c.saveSession(ctx)
 		return nil
 
 	default:
@@ -155,42 +226,124 @@ func (c *Connection) connectHelper() error {
 	}
 }
 
+// Used internally in order to retrieve the server's version and to check whether it is supported by the SDK
+// The version is stored in the connection
+// If failover URLs are configured, the first available server is selected and becomes the active server
+/** Errors
+- nil
+- Version errors
+- ErrInvalidVersion
+- ErrUnsupportedVersion
+- ErrNoServerAvailable
+*/
+func (c *Connection) checkServerVersion(ctx context.Context) error {
+	index := c.activeIndex()
+	var version VersionResponse
+	var currentV *semver.Version
+	var err error
+	if len(c.endpoints) > 1 {
+		index, version, currentV, err = c.probeEndpoints(ctx)
+	} else {
+		version, currentV, err = c.serverVersionAt(ctx, c.endpoints[index])
+	}
+
+	// Set the version in the connection
+	// Is already set here so it can be used in error messages as `c.SmarthomeVersion`
+	if version.Version != "" {
+		c.SmarthomeVersion = version.Version
+		c.SmarthomeGoVersion = version.GoVersion
+	}
+	if err != nil {
+		return err
+	}
+
+	// Store the parsed version so that it can be used for capability checks
+	c.mu.Lock()
+	c.active = index
+	c.serverVersion = currentV
+	c.mu.Unlock()
+	return nil
+}
+
+// Used internally in order to retrieve the version of a specific server and to check whether it is supported by the SDK
+// The version response is also returned if the version is not supported
+/** Errors
+- nil
+- Version errors
+- ErrInvalidVersion
+- ErrUnsupportedVersion
+*/
+func (c *Connection) serverVersionAt(ctx context.Context, base *url.URL) (VersionResponse, *semver.Version, error) {
+	// Retrieve the server's version
+	version, err := c.versionAt(ctx, base)
+	if err != nil {
+		return VersionResponse{}, nil, err
+	}
+
+	// Check Smarthome version compatibility
+	supportedV, err := semver.NewConstraint(fmt.Sprintf("^%s", MinSmarthomeVersion))
+	if err != nil {
+		// This must not happen (tests)
+		// If this happens, the best thing is to abort the connection
+		return version, nil, ErrInvalidVersion
+	}
+
+	currentV, err := semver.NewVersion(version.Version)
+	if err != nil {
+		// This must also not happen
+		// If this happens, the best thing is to abort the connection
+		return version, nil, ErrInvalidVersion
+	}
+
+	// Perform the version comparison
+	if !supportedV.Check(currentV) {
+		// Would not be supported
+		return version, nil, ErrUnsupportedVersion
+	}
+	return version, currentV, nil
+}
+
 // Used internally to send a login request
 // When the authentication mode is set to `AuthMethodCookie-XXX`, the response cookie is saved
-// However, for `AuthMethodQuery-XXX`, it serves the purpose of validating the provided credentials beforehand
+// However, for `AuthMethodQuery-XXX` and `AuthMethodHeader-XXX`, it serves the purpose of validating the provided credentials beforehand
 // If the authentication mode is sey set to `AuthMethodNone`, the function call is omitted
-func (c *Connection) doLogin() (
-	*http.Cookie,
-	*tokenLoginResponse,
-	error,
+func (c *Connection) doLogin(ctx context.Context) (
+	_ *http.Cookie,
+	_ *tokenLoginResponse,
+	err error,
 ) {
-	u := c.SmarthomeURL
+	defer func() { c.logLogin(ctx, err) }()
 	// The default path is the user login
-	u.Path = "/api/login"
+	u := c.endpointURL("/api/login")
 	// If authentication should use a token, change the path
-	if c.authMethod == AuthMethodQueryToken || c.authMethod == AuthMethodCookieToken {
-		u.Path = "/api/login/token"
+	if c.usesTokenAuth() {
+		u = c.endpointURL("/api/login/token")
+	}
+	// Use a snapshot of the credentials, so that the lock is not held during the request
+	creds, err := c.credentials(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var loginBody []byte
 	var loginBodyErr error
 
-	if c.authMethod == AuthMethodQueryPassword || c.authMethod == AuthMethodCookiePassword {
+	if c.usesPasswordAuth() {
 		loginBody, loginBodyErr = json.Marshal(struct {
 			Username string `json:"username"`
 			Password string `json:"password"`
 		}{
-			Username: c.credStore.Username,
-			Password: c.credStore.Password,
+			Username: creds.Username,
+			Password: creds.Password,
 		})
 		if loginBodyErr != nil {
 			return nil, nil, loginBodyErr
 		}
-	} else if c.authMethod == AuthMethodQueryToken || c.authMethod == AuthMethodCookieToken {
+	} else if c.usesTokenAuth() {
 		loginBody, loginBodyErr = json.Marshal(struct {
 			Token string `json:"token"`
 		}{
-			Token: c.credStore.Token,
+			Token: creds.Token,
 		})
 		if loginBodyErr != nil {
 			return nil, nil, loginBodyErr
@@ -199,7 +352,8 @@ func (c *Connection) doLogin() (
 		panic("unreachable")
 	}
 	// Create a login request
-	r, err := http.NewRequest(
+	r, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodPost,
 		u.String(),
 		bytes.NewBuffer(loginBody),
@@ -207,16 +361,17 @@ func (c *Connection) doLogin() (
 	if err != nil {
 		return nil, nil, err
 	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("User-Agent", c.userAgent)
 	// Perform the login request
-	client := &http.Client{}
-	res, err := client.Do(r)
+	res, err := c.roundTrip(EndpointLogin, 1, r)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, contextError(ctx, err)
 	}
 	defer res.Body.Close()
 	switch res.StatusCode {
 	case 200:
-		if c.authMethod == AuthMethodQueryPassword || c.authMethod == AuthMethodCookiePassword {
+		if c.usesPasswordAuth() {
 			// should not happen: this is a bug
 			panic("unreachable")
 		}
@@ -226,8 +381,8 @@ func (c *Connection) doLogin() (
 			return nil, nil, ErrReadResponseBody
 		}
 		var parsedBody tokenLoginResponse
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return nil, nil, ErrReadResponseBody
+		if err := c.decode(EndpointLogin, resBody, &parsedBody); err != nil {
+			return nil, nil, err
 		}
 		var returnCookie *http.Cookie
 		for _, cookie := range res.Cookies() {
@@ -241,6 +396,10 @@ func (c *Connection) doLogin() (
 		}
 		return returnCookie, &parsedBody, nil
 	case 204:
+		// Remember the username, it could have been changed by the credential provider
+		c.mu.Lock()
+		c.credStore.Username = creds.Username
+		c.mu.Unlock()
 		for _, cookie := range res.Cookies() {
 			if cookie.Name == "session" {
 				return cookie, nil, nil
@@ -248,13 +407,13 @@ func (c *Connection) doLogin() (
 		}
 		return nil, nil, ErrNoCookiesSent
 	case 401:
-		return nil, nil, ErrInvalidCredentials
+		return nil, nil, newAPIError(res, ErrInvalidCredentials)
 	case 500:
-		return nil, nil, ErrInternalServerError
+		return nil, nil, newAPIError(res, ErrInternalServerError)
 	case 503:
-		return nil, nil, ErrServiceUnavailable
+		return nil, nil, newAPIError(res, ErrServiceUnavailable)
 	default:
-		return nil, nil, fmt.Errorf("unknown response code: %s", res.Status)
+		return nil, nil, newAPIError(res, ErrUnknownResponseCode)
 	}
 }
 
@@ -263,13 +422,100 @@ func (c *Connection) GetUsername() (string, error) {
 	if c.authMethod == AuthMethodNone {
 		return "", ErrInvalidFunctionAuthMethod
 	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.credStore.Username, nil
 }
 
 // Only works on token-based authentication methods
 func (c *Connection) GetTokenClientLabel() (string, error) {
-	if c.authMethod != AuthMethodQueryToken && c.authMethod != AuthMethodCookieToken {
+	if !c.usesTokenAuth() {
 		return "", ErrInvalidFunctionAuthMethod
 	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.tokenClientName, nil
 }
+
+// Used internally in order to check whether the user authenticates using a username and a password
+func (c *Connection) usesPasswordAuth() bool {
+	return c.authMethod == AuthMethodCookiePassword || c.authMethod == AuthMethodQueryPassword || c.authMethod == AuthMethodHeaderPassword
+}
+
+// Used internally in order to check whether the user authenticates using a token
+func (c *Connection) usesTokenAuth() bool {
+	return c.authMethod == AuthMethodCookieToken || c.authMethod == AuthMethodQueryToken || c.authMethod == AuthMethodHeaderToken
+}
+
+// Used internally in order to check whether a session cookie is sent with every request
+func (c *Connection) usesCookieAuth() bool {
+	return c.authMethod == AuthMethodCookiePassword || c.authMethod == AuthMethodCookieToken
+}
+
+// Used internally in order to check whether the credentials are sent with every request, using the URL query or a header
+func (c *Connection) sendsCredentials() bool {
+	return c.authMethod != AuthMethodNone && !c.usesCookieAuth()
+}
+
+// Used internally in order to obtain a new session cookie after the server has rejected the current one
+// This happens if the Smarthome server has been restarted, because the server does not persist its sessions
+// The rejected cookie is passed so that concurrent requests which were rejected at the same time only cause one re-login
+// The relogin hook is notified about every attempt, regardless of its outcome
+/** Errors
+- nil
+- Login errors
+*/
+func (c *Connection) relogin(ctx context.Context, rejected *http.Cookie) error {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	// Another goroutine has already replaced the rejected cookie
+	if c.cookie() != rejected {
+		return nil
+	}
+	cookie, _, err := c.doLogin(ctx)
+	if err == nil {
+		c.mu.Lock()
+		c.sessionCookie = cookie
+		c.mu.Unlock()
+		c.saveSession(ctx)
+	}
+	if c.reloginHook != nil {
+		c.reloginHook(err)
+	}
+	return err
+}
+
+// Used internally in order to access the current session cookie
+func (c *Connection) cookie() *http.Cookie {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sessionCookie
+}
+
+// Used internally in order to access a copy of the stored credentials
+// If a credential provider is used, the credentials are obtained from it
+// The username of token-based authentication is always taken from the store because it is sent by the server
+/** Errors
+- nil
+- CredentialProvider errors
+*/
+func (c *Connection) credentials(ctx context.Context) (credStore, error) {
+	c.mu.RLock()
+	creds := c.credStore
+	provider := c.credProvider
+	c.mu.RUnlock()
+	if provider == nil {
+		return creds, nil
+	}
+	provided, err := provider.Credentials(ctx)
+	if err != nil {
+		return credStore{}, err
+	}
+	if c.usesTokenAuth() {
+		creds.Token = provided.Token
+	} else {
+		creds.Username = provided.Username
+		creds.Password = provided.Password
+	}
+	return creds, nil
+}
@@ -1,9 +1,13 @@
 package sdk
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gorilla/sessions"
 	"github.com/stretchr/testify/assert"
@@ -26,6 +30,14 @@ func TestInit(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	})
 
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
 	ts := httptest.NewServer(r)
 	defer ts.Close()
 	// End test server
@@ -42,3 +54,79 @@ func TestInit(t *testing.T) {
 	assert.Error(t, c3.Connect())
 	assert.EqualError(t, c3.Connect(), ErrConnFailed.Error())
 }
+
+func TestConnectContextCanceled(t *testing.T) {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodNone)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = c.ConnectContext(ctx)
+	assert.ErrorIs(t, err, ErrCanceled)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, ErrConnFailed)
+	assert.False(t, c.ready)
+}
+
+func TestCookieRelogin(t *testing.T) {
+	// Changing the key invalidates all sessions, just like a restart of the Smarthome server
+	var key atomic.Value
+	key.Store("key-1")
+	store := func() *sessions.CookieStore {
+		return sessions.NewCookieStore([]byte(key.Load().(string)))
+	}
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		session, _ := store().Get(r, "session")
+		session.Values["valid"] = true
+		assert.NoError(t, session.Save(r, w))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/power/set", func(w http.ResponseWriter, r *http.Request) {
+		session, err := store().Get(r, "session")
+		if err != nil || session.Values["valid"] != true {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	relogins := make([]error, 0)
+	c, err := NewConnection(ts.URL, AuthMethodCookiePassword, WithReloginHook(func(err error) {
+		relogins = append(relogins, err)
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, c.UserLogin("test", "test"))
+	assert.NoError(t, c.SetPower("s1", true))
+	assert.Len(t, relogins, 0)
+
+	// Simulate a restart of the server
+	key.Store("key-2")
+	assert.NoError(t, c.SetPower("s1", true))
+	assert.Equal(t, []error{nil}, relogins)
+	assert.NoError(t, c.SetPower("s1", false))
+	assert.Len(t, relogins, 1)
+}
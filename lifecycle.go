@@ -0,0 +1,119 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Ends the current session and resets the connection to its uninitialized state
+// For cookie-based authentication methods, the session is invalidated on the server and removed from the session store as well
+// The stored credentials are removed, a new login is required before the connection can be used again
+func (c *Connection) Logout() error {
+	return c.LogoutContext(context.Background())
+}
+
+// Same as `Logout`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+// The local state and the stored session are reset even if the server could not be reached
+/** Errors
+- nil
+- ErrNotInitialized
+- ErrConnectionClosed
+- ErrConnFailed
+- ErrUnknownResponseCode
+- SessionStore errors
+*/
+func (c *Connection) LogoutContext(ctx context.Context) error {
+	if err := c.checkReady(); err != nil {
+		return err
+	}
+	defer c.resetSession()
+	// URL-query and header authentication do not use a session on the server
+	if !c.usesCookieAuth() {
+		return nil
+	}
+	err := c.logout(ctx)
+	// A session which is still valid must not remain stored after the user has logged out
+	if c.sessionStore != nil {
+		if deleteErr := c.sessionStore.Delete(); deleteErr != nil {
+			return errors.Join(err, deleteErr)
+		}
+	}
+	return err
+}
+
+// Used internally in order to invalidate the session on the server
+func (c *Connection) logout(ctx context.Context) error {
+	// A single attempt is used, the session must not be renewed by a re-login
+	res, err := c.sendOnce(ctx, apiRequest{
+		endpoint: "Logout",
+		path:     "/api/logout",
+		method:   Get,
+		attempt:  1,
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	// An unauthorized session has already ended
+	case 200, 204, 401:
+		return nil
+	}
+	return newAPIError(res, ErrUnknownResponseCode)
+}
+
+// Closes the connection and releases its resources
+// Idle HTTP connections are closed and background goroutines, for example watchers, are stopped
+// The session is not ended on the server, use `Logout` before `Close` in order to do so
+// Every function called after `Close` returns `ErrConnectionClosed`
+// Calling `Close` multiple times is safe
+func (c *Connection) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.ready = false
+	close(c.done)
+	c.mu.Unlock()
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// Used internally in order to check if the connection can be used
+/** Errors
+- nil
+- ErrNotInitialized
+- ErrConnectionClosed
+*/
+func (c *Connection) checkReady() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return ErrConnectionClosed
+	}
+	if !c.ready {
+		return ErrNotInitialized
+	}
+	return nil
+}
+
+// Used internally in order to check if `Close` has been called
+func (c *Connection) isClosed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.closed
+}
+
+// Used internally in order to remove the session and the credentials from the connection
+func (c *Connection) resetSession() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ready = false
+	c.sessionCookie = &http.Cookie{}
+	c.credStore = credStore{}
+	c.credProvider = nil
+	c.tokenClientName = ""
+}
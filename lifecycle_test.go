@@ -0,0 +1,66 @@
+package sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogoutAndClose(t *testing.T) {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session"})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	logouts := 0
+	r.HandleFunc("/api/logout", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		assert.NoError(t, err)
+		assert.Equal(t, "session", cookie.Value)
+		logouts++
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodCookiePassword)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, c.Logout(), ErrNotInitialized)
+
+	assert.NoError(t, c.UserLogin("test", "test"))
+	assert.NoError(t, c.Logout())
+	assert.Equal(t, 1, logouts)
+	assert.ErrorIs(t, c.SetPower("s1", true), ErrNotInitialized)
+	username, err := c.GetUsername()
+	assert.NoError(t, err)
+	assert.Empty(t, username)
+
+	// The connection can be used again after a new login
+	assert.NoError(t, c.UserLogin("test", "test"))
+	assert.NoError(t, c.Close())
+	assert.NoError(t, c.Close())
+
+	assert.ErrorIs(t, c.SetPower("s1", true), ErrConnectionClosed)
+	assert.ErrorIs(t, c.Logout(), ErrConnectionClosed)
+	assert.ErrorIs(t, c.UserLogin("test", "test"), ErrConnectionClosed)
+	_, err = c.HealthCheck()
+	assert.ErrorIs(t, err, ErrConnectionClosed)
+	assert.Equal(t, 1, logouts)
+}
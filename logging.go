@@ -0,0 +1,91 @@
+package sdk
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Logs structured records for every request using the specified logger
+// Every record contains the endpoint, HTTP method, path, status, duration, attempt and authentication method
+// Credentials are never logged: query parameters containing them are redacted
+// By default, the SDK does not log anything
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) error {
+		o.logger = logger
+		return nil
+	}
+}
+
+// Used internally in order to log the outcome of a single request
+// Successful requests are logged at debug level, failed requests at warning level
+func (c *Connection) logRequest(req *http.Request, endpoint string, attempt int, start time.Time, res *http.Response, err error) {
+	if c.logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("endpoint", endpoint),
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.String("url", redactURL(req.URL)),
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("attempt", attempt),
+		slog.String("auth", c.authMethod.String()),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", redactError(err).Error()))
+		c.logger.LogAttrs(req.Context(), slog.LevelWarn, "smarthome request failed", attrs...)
+		return
+	}
+	attrs = append(attrs, slog.Int("status", res.StatusCode))
+	level := slog.LevelDebug
+	if res.StatusCode >= 500 {
+		level = slog.LevelWarn
+	}
+	c.logger.LogAttrs(req.Context(), level, "smarthome request", attrs...)
+}
+
+// Used internally in order to log the outcome of a login
+func (c *Connection) logLogin(ctx context.Context, err error) {
+	if c.logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("auth", c.authMethod.String()),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "smarthome login failed", attrs...)
+		return
+	}
+	c.logger.LogAttrs(ctx, slog.LevelInfo, "smarthome login succeeded", attrs...)
+}
+
+// Used internally in order to log that the connection has switched to another server
+func (c *Connection) logFailover(ctx context.Context, from *url.URL, to *url.URL, err error) {
+	if c.logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("from", redactURL(from)),
+		slog.String("to", redactURL(to)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "smarthome server switched, but the login failed", attrs...)
+		return
+	}
+	c.logger.LogAttrs(ctx, slog.LevelWarn, "smarthome server switched", attrs...)
+}
+
+// Used internally in order to log that the server has rejected the session cookie
+func (c *Connection) logSessionRejected(ctx context.Context) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.LogAttrs(ctx, slog.LevelInfo, "smarthome session was rejected, logging in again",
+		slog.String("auth", c.authMethod.String()),
+	)
+}
@@ -0,0 +1,68 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogging(t *testing.T) {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session"})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.HandleFunc("/api/power/set", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	c, err := NewConnection(ts.URL, AuthMethodQueryPassword, WithLogger(logger))
+	assert.NoError(t, err)
+	assert.NoError(t, c.UserLogin("user", "secret-password"))
+	assert.ErrorIs(t, c.SetPower("s1", true), ErrPermissionDenied)
+
+	assert.NotContains(t, buf.String(), "secret-password")
+
+	records := make([]map[string]interface{}, 0)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	assert.Len(t, records, 4)
+
+	assert.Equal(t, EndpointVersion, records[0]["endpoint"])
+	assert.Equal(t, EndpointLogin, records[1]["endpoint"])
+	assert.Equal(t, "smarthome login succeeded", records[2]["msg"])
+
+	power := records[3]
+	assert.Equal(t, "SetPower", power["endpoint"])
+	assert.Equal(t, "POST", power["method"])
+	assert.Equal(t, "/api/power/set", power["path"])
+	assert.Equal(t, float64(http.StatusForbidden), power["status"])
+	assert.Equal(t, float64(1), power["attempt"])
+	assert.Equal(t, "query-password", power["auth"])
+	assert.Contains(t, power["url"], "password=REDACTED")
+	assert.Contains(t, power, "duration")
+}
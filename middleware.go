@@ -0,0 +1,68 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Sends a single HTTP request and returns its response, just like `http.RoundTripper`
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Wraps the sending of a request, for example in order to add headers, log requests or measure latency
+// A middleware must call `next` in order to send the request, unless it wants to short-circuit it
+// The name of the endpoint which is requested can be obtained using `EndpointName(req.Context())`
+// Errors returned by a middleware are passed to the caller of the API function unchanged
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// Names of the endpoints which are not related to a single API function
+const (
+	EndpointLogin       = "Login"
+	EndpointHealthCheck = "HealthCheck"
+	EndpointVersion     = "Version"
+	EndpointDo          = "Do"
+)
+
+// Used as the context key for the endpoint name
+type endpointKey struct{}
+
+// Returns the name of the endpoint to which a request is sent, for example `SetPower`
+// For requests which are sent by API functions, the name matches the function's name (without the `Context` suffix)
+// Login, health and version requests use `EndpointLogin`, `EndpointHealthCheck` and `EndpointVersion`
+// Returns an empty string if the context does not belong to a request which was sent by the SDK
+func EndpointName(ctx context.Context) string {
+	name, _ := ctx.Value(endpointKey{}).(string)
+	return name
+}
+
+// Adds middlewares which wrap every request sent by this connection, including login, health and version requests
+// The first middleware which was added is the outermost one, meaning that it is called first
+// Retried requests pass through the middlewares on every attempt
+func (c *Connection) Use(middlewares ...Middleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// Used internally in order to send a prepared request through the middleware chain
+// The endpoint name is attached to the request's context
+// The attempt starts at 1 and is only increased by retries, it is used for logging
+func (c *Connection) roundTrip(endpoint string, attempt int, req *http.Request) (*http.Response, error) {
+	req = req.WithContext(context.WithValue(req.Context(), endpointKey{}, endpoint))
+
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return nil, ErrConnectionClosed
+	}
+	next := RoundTripFunc(c.client.Do)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		next = c.middlewares[i](next)
+	}
+	c.mu.RUnlock()
+
+	start := time.Now()
+	res, err := next(req)
+	c.logRequest(req, endpoint, attempt, start, res, err)
+	return res, err
+}
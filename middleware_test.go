@@ -0,0 +1,81 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "correlation", r.Header.Get("X-Correlation-Id"))
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "correlation", r.Header.Get("X-Correlation-Id"))
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session"})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "correlation", r.Header.Get("X-Correlation-Id"))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.HandleFunc("/api/power/set", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "correlation", r.Header.Get("X-Correlation-Id"))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodCookiePassword)
+	assert.NoError(t, err)
+
+	order := make([]string, 0)
+	endpoints := make([]string, 0)
+	c.Use(
+		func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, "outer")
+				endpoints = append(endpoints, EndpointName(req.Context()))
+				return next(req)
+			}
+		},
+		func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, "inner")
+				req.Header.Set("X-Correlation-Id", "correlation")
+				return next(req)
+			}
+		},
+	)
+
+	assert.NoError(t, c.UserLogin("test", "test"))
+	_, err = c.HealthCheck()
+	assert.NoError(t, err)
+	assert.NoError(t, c.SetPower("s1", true))
+
+	assert.Equal(t, []string{EndpointVersion, EndpointLogin, EndpointHealthCheck, "SetPower"}, endpoints)
+	assert.Equal(t, []string{"outer", "inner", "outer", "inner", "outer", "inner", "outer", "inner"}, order)
+
+	// Errors of middlewares are passed to the caller
+	errAudit := errors.New("audit failed")
+	c.Use(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			return nil, errAudit
+		}
+	})
+	assert.ErrorIs(t, c.SetPower("s1", true), errAudit)
+}
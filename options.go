@@ -0,0 +1,135 @@
+package sdk
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Configures a connection when passed to `NewConnection`
+// Options are applied in the order in which they are specified
+type Option func(o *options) error
+
+// Collects the configuration of a connection before it is created
+type options struct {
+	// The client which is used as a template for the connection's HTTP client
+	httpClient *http.Client
+	// If set, overrides the transport of the HTTP client
+	transport http.RoundTripper
+	// If set, overrides the timeout of the HTTP client
+	timeout time.Duration
+	// The `User-Agent` header which is sent with every request
+	userAgent string
+	// Is called after every automatic re-login of a cookie-authenticated connection
+	reloginHook func(err error)
+	// Specifies how requests which failed due to transient errors are retried
+	retryPolicy RetryPolicy
+	// Receives structured records about every request, nil disables logging
+	logger *slog.Logger
+	// If set, the session is saved to it after every successful login
+	sessionStore SessionStore
+	// Configures TLS for every request
+	tls tlsOptions
+	// Specifies how unknown fields in responses are handled
+	decodeMode DecodeMode
+	// Is called whenever a response contains unknown fields
+	unknownFieldsHandler func(endpoint string, fields []string)
+	// Limit the requests of every endpoint class
+	limits map[EndpointClass]Limit
+	// If set, a circuit breaker stops sending requests while the server is unavailable
+	circuitBreaker *CircuitBreakerPolicy
+	// Standby servers which are used if the primary server is unavailable
+	failoverURLs []*url.URL
+	// Is called whenever the connection switches to another server
+	failoverHook func(from string, to string, err error)
+}
+
+// Returns the options which are used if no option is specified
+func defaultOptions() options {
+	return options{
+		userAgent: fmt.Sprintf("SmarthomeSDK/%s", Version),
+	}
+}
+
+// Creates the HTTP client which is shared by every request of the connection
+// The client passed by `WithHTTPClient` is copied so that it is never modified by the SDK
+// TLS options are applied to a clone of the client's transport
+func (o options) buildClient() (*http.Client, error) {
+	client := &http.Client{}
+	if o.httpClient != nil {
+		copied := *o.httpClient
+		client = &copied
+	}
+	if o.transport != nil {
+		client.Transport = o.transport
+	}
+	if o.timeout != 0 {
+		client.Timeout = o.timeout
+	}
+	if o.tls.isSet() {
+		transport, err := o.tls.apply(client.Transport)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = transport
+	}
+	return client, nil
+}
+
+// Uses the specified HTTP client for every request, including login, health and version requests
+// The client is copied, which means that later modifications of it have no effect on the connection
+// Can be combined with `WithTransport` and `WithDefaultTimeout` in order to override the client's settings
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) error {
+		if client == nil {
+			return fmt.Errorf("%w: HTTP client must not be nil", ErrInvalidOption)
+		}
+		o.httpClient = client
+		return nil
+	}
+}
+
+// Uses the specified transport for every request
+// Can be used in order to configure proxies, connection pooling or test doubles
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) error {
+		if transport == nil {
+			return fmt.Errorf("%w: transport must not be nil", ErrInvalidOption)
+		}
+		o.transport = transport
+		return nil
+	}
+}
+
+// Sets a timeout which applies to every request of the connection
+// Homescript-related functions which accept an explicit timeout are additionally limited by their own timeout
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(o *options) error {
+		if timeout < 0 {
+			return fmt.Errorf("%w: timeout must not be negative", ErrInvalidOption)
+		}
+		o.timeout = timeout
+		return nil
+	}
+}
+
+// Overrides the `User-Agent` header which is sent with every request
+// The default value is `SmarthomeSDK/<version>`
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) error {
+		o.userAgent = userAgent
+		return nil
+	}
+}
+
+// Registers a function which is called whenever a cookie-authenticated connection logs in again
+// This happens if the server rejects the session cookie, for example after the Smarthome server has been restarted
+// The error is nil if the re-login was successful, otherwise the original request fails with the same error
+func WithReloginHook(hook func(err error)) Option {
+	return func(o *options) error {
+		o.reloginHook = hook
+		return nil
+	}
+}
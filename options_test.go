@@ -0,0 +1,81 @@
+package sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingTransport struct {
+	requests int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.requests, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestOptions(t *testing.T) {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-agent", r.UserAgent())
+		sessionStore := sessions.NewCookieStore([]byte("key"))
+		session, _ := sessionStore.Get(r, "session")
+		session.Values["valid"] = true
+		assert.NoError(t, session.Save(r, w))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-agent", r.UserAgent())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-agent", r.UserAgent())
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	transport := &countingTransport{}
+	base := &http.Client{Timeout: time.Minute}
+	c, err := NewConnection(
+		ts.URL,
+		AuthMethodCookiePassword,
+		WithHTTPClient(base),
+		WithTransport(transport),
+		WithDefaultTimeout(time.Second),
+		WithUserAgent("test-agent"),
+	)
+	assert.NoError(t, err)
+
+	// The client passed by the user must not be modified
+	assert.Nil(t, base.Transport)
+	assert.Equal(t, time.Minute, base.Timeout)
+	assert.Equal(t, time.Second, c.client.Timeout)
+
+	// Version, login and health requests are sent using the shared client
+	assert.NoError(t, c.UserLogin("test", "test"))
+	status, err := c.HealthCheck()
+	assert.NoError(t, err)
+	assert.Equal(t, StatusHealthy, status)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&transport.requests))
+
+	// Invalid options are rejected
+	_, err = NewConnection(ts.URL, AuthMethodNone, WithHTTPClient(nil))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewConnection(ts.URL, AuthMethodNone, WithDefaultTimeout(-time.Second))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+}
@@ -0,0 +1,125 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Configures how `SetPowerMany` sends its power requests
+type SetPowerManyOptions struct {
+	// The maximum number of power requests which are sent at the same time
+	// Values less than 1 are treated as 1, meaning that the switches are processed sequentially
+	Concurrency int
+	// The order in which the switches are processed, ids which are not contained in the states are ignored
+	// Switches which are not listed are processed afterwards in alphabetical order
+	Order []string
+	// The delay between starting two consecutive power requests, zero means no delay
+	// Can be used in order to avoid the inrush current of many devices being turned on at once
+	Delay time.Duration
+}
+
+// The outcome of setting the power of a single switch using `SetPowerMany`
+type SetPowerResult struct {
+	// The id of the switch
+	Switch string
+	// The requested power state
+	PowerOn bool
+	// Nil if the power was set successfully, otherwise the error which `SetPower` would have returned
+	Err error
+}
+
+// Sets the power of many switches, the keys of `states` are switch ids
+// Unlike calling `SetPower` in a loop, a failure does not stop the remaining switches from being processed
+// Returns one result per switch in the order in which the switches were processed
+// The error joins the errors of every failed switch, it is nil if every switch was set successfully
+// If `ctx` is canceled, the switches which have not been processed yet fail with an error matching `ErrCanceled`
+/** Errors
+- nil
+- ErrNotInitialized
+- ErrConnectionClosed
+- SetPower errors (joined)
+*/
+func (c *Connection) SetPowerMany(ctx context.Context, states map[string]bool, opts SetPowerManyOptions) ([]SetPowerResult, error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+	order := powerOrder(states, opts.Order)
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]SetPowerResult, len(order))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for index, id := range order {
+		results[index] = SetPowerResult{Switch: id, PowerOn: states[id]}
+		if err := waitForPowerSlot(ctx, slots, index > 0, opts.Delay); err != nil {
+			results[index].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func(result *SetPowerResult) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result.Err = c.SetPowerContext(ctx, result.Switch, result.PowerOn)
+		}(&results[index])
+	}
+	wg.Wait()
+
+	errs := make([]error, 0)
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("switch `%s`: %w", result.Switch, result.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// Used internally in order to determine the order in which `SetPowerMany` processes the switches
+func powerOrder(states map[string]bool, preferred []string) []string {
+	order := make([]string, 0, len(states))
+	seen := make(map[string]bool, len(states))
+	for _, id := range preferred {
+		if _, found := states[id]; found && !seen[id] {
+			order = append(order, id)
+			seen[id] = true
+		}
+	}
+	remaining := make([]string, 0, len(states)-len(order))
+	for id := range states {
+		if !seen[id] {
+			remaining = append(remaining, id)
+		}
+	}
+	sort.Strings(remaining)
+	return append(order, remaining...)
+}
+
+// Used internally in order to wait until `SetPowerMany` may start the next power request
+// The delay is only applied between two requests, not before the first one
+/** Errors
+- nil
+- ErrCanceled
+*/
+func waitForPowerSlot(ctx context.Context, slots chan struct{}, delay bool, duration time.Duration) error {
+	if delay && duration > 0 {
+		timer := time.NewTimer(duration)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &canceledError{cause: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return &canceledError{cause: ctx.Err()}
+	case slots <- struct{}{}:
+		return nil
+	}
+}
@@ -0,0 +1,100 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetPowerMany(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	var inFlight, maxInFlight atomic.Int32
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/power/set", func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			previous := maxInFlight.Load()
+			if current <= previous || maxInFlight.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		var data struct {
+			Switch  string `json:"switch"`
+			PowerOn bool   `json:"powerOn"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&data))
+		mu.Lock()
+		received = append(received, data.Switch)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		if data.Switch == "invalid" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodNone)
+	assert.NoError(t, err)
+
+	states := map[string]bool{"a": true, "b": false, "c": true, "d": true, "invalid": true}
+	_, err = c.SetPowerMany(context.Background(), states, SetPowerManyOptions{})
+	assert.ErrorIs(t, err, ErrNotInitialized)
+	assert.NoError(t, c.Connect())
+
+	// The switches are processed sequentially in the specified order, a failure does not stop the others
+	results, err := c.SetPowerMany(context.Background(), states, SetPowerManyOptions{Order: []string{"d", "unknown", "invalid", "d"}})
+	assert.ErrorIs(t, err, ErrInvalidSwitch)
+	assert.Contains(t, err.Error(), "switch `invalid`")
+	assert.Equal(t, []string{"d", "invalid", "a", "b", "c"}, received)
+	assert.Equal(t, int32(1), maxInFlight.Load())
+	assert.Len(t, results, 5)
+	for _, result := range results {
+		assert.Equal(t, states[result.Switch], result.PowerOn)
+		if result.Switch == "invalid" {
+			assert.ErrorIs(t, result.Err, ErrInvalidSwitch)
+		} else {
+			assert.NoError(t, result.Err)
+		}
+	}
+
+	// The concurrency is limited
+	delete(states, "invalid")
+	results, err = c.SetPowerMany(context.Background(), states, SetPowerManyOptions{Concurrency: 2})
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+	assert.Equal(t, int32(2), maxInFlight.Load())
+
+	// Consecutive requests are delayed
+	start := time.Now()
+	_, err = c.SetPowerMany(context.Background(), states, SetPowerManyOptions{Concurrency: 4, Delay: 30 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	// Switches which have not been processed before the context is canceled fail
+	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Millisecond)
+	defer cancel()
+	results, err = c.SetPowerMany(ctx, states, SetPowerManyOptions{Delay: 30 * time.Millisecond})
+	assert.ErrorIs(t, err, ErrCanceled)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[3].Err, ErrCanceled)
+}
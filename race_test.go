@@ -0,0 +1,121 @@
+package sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+)
+
+// Starts a server which implements every endpoint used by the SDK
+// Changing the returned key invalidates all sessions, just like a restart of the Smarthome server
+func newRaceTestServer(t *testing.T) (*httptest.Server, *atomic.Value) {
+	var key atomic.Value
+	key.Store("key-1")
+	store := func() *sessions.CookieStore {
+		return sessions.NewCookieStore([]byte(key.Load().(string)))
+	}
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/login/token", func(w http.ResponseWriter, r *http.Request) {
+		session, _ := store().Get(r, "session")
+		session.Values["valid"] = true
+		assert.NoError(t, session.Save(r, w))
+		assert.NoError(t, json.NewEncoder(w).Encode(tokenLoginResponse{
+			Username:   "test",
+			TokenLabel: "race",
+		}))
+	})
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		session, err := store().Get(r, "session")
+		if err != nil || session.Values["valid"] != true {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case strings.Contains(r.URL.Path, "/list"):
+			_, _ = w.Write([]byte("[]"))
+		case strings.HasPrefix(r.URL.Path, "/api/homescript/run"), strings.HasPrefix(r.URL.Path, "/api/homescript/lint"):
+			assert.NoError(t, json.NewEncoder(w).Encode(HomescriptResponse{Success: true}))
+		default:
+			_, _ = w.Write([]byte("{}"))
+		}
+	})
+
+	return httptest.NewServer(r), &key
+}
+
+func TestConcurrentUse(t *testing.T) {
+	ts, key := newRaceTestServer(t)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodCookieToken)
+	assert.NoError(t, err)
+	assert.NoError(t, c.TokenLogin("token"))
+
+	calls := []func() error{
+		func() error { _, err := c.GetPersonalSwitches(); return err },
+		func() error { _, err := c.GetAllSwitches(); return err },
+		func() error { return c.SetPower("s1", true) },
+		func() error { _, err := c.ListAutomations(); return err },
+		func() error { _, err := c.GetDebugInfo(); return err },
+		func() error { _, err := c.RunHomescriptCode("print(1)", nil, time.Second); return err },
+		func() error { _, err := c.RunHomescriptById("test", nil, time.Second); return err },
+		func() error { _, err := c.LintHomescriptCode("print(1)", nil, time.Second); return err },
+		func() error { _, err := c.LintHomescriptById("test", nil, time.Second); return err },
+		func() error { return c.CreateHomescript(HomescriptRequest{Id: "test"}) },
+		func() error { return c.ModifyHomescript(HomescriptRequest{Id: "test"}) },
+		func() error { return c.DeleteHomescript("test") },
+		func() error { _, err := c.GetHomescript("test"); return err },
+		func() error { _, err := c.ListHomescript(); return err },
+		func() error { _, err := c.ListHomescriptArgsOfHmsId("test"); return err },
+		func() error { _, err := c.ListHomescriptWithArgs(); return err },
+		func() error { _, err := c.HealthCheck(); return err },
+		func() error { _, err := c.Version(); return err },
+		func() error { _, err := c.GetUsername(); return err },
+		func() error { _, err := c.GetTokenClientLabel(); return err },
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		for _, call := range calls {
+			wg.Add(1)
+			go func(call func() error) {
+				defer wg.Done()
+				for i := 0; i < 10; i++ {
+					assert.NoError(t, call())
+				}
+			}(call)
+		}
+	}
+
+	// Invalidate the sessions while the requests are running in order to trigger concurrent re-logins
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(5 * time.Millisecond)
+		key.Store("key-2")
+	}()
+
+	wg.Wait()
+}
@@ -0,0 +1,173 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Groups requests which are limited together using `WithLimit`
+type EndpointClass uint8
+
+const (
+	// Read requests, for example `GetPersonalSwitches`
+	EndpointClassRead EndpointClass = iota
+	// Requests which set the power of a switch, meaning `SetPower`
+	EndpointClassPower
+	// Requests which execute or lint Homescript code, for example `RunHomescriptById`
+	EndpointClassHomescript
+	// Every other request which modifies data, for example `CreateHomescript`
+	EndpointClassWrite
+)
+
+// Specifies how many requests of an endpoint class may be sent
+// Every attempt of a request counts, including retries
+// Login, health and version requests are never limited
+type Limit struct {
+	// The sustained number of requests per second, zero disables rate limiting
+	Rate float64
+	// The number of requests which may be sent at once after a period of inactivity
+	// Values less than 1 are treated as 1
+	Burst int
+	// The maximum number of requests which are in flight at the same time, zero means no limit
+	// A request is in flight until its response has been read completely
+	MaxInFlight int
+}
+
+// Limits the requests of the specified endpoint class
+// Callers which exceed the limit are blocked until the request may be sent or their context is canceled
+// Can be used multiple times in order to limit several endpoint classes
+func WithLimit(class EndpointClass, limit Limit) Option {
+	return func(o *options) error {
+		if class > EndpointClassWrite {
+			return fmt.Errorf("%w: unknown endpoint class %d", ErrInvalidOption, class)
+		}
+		if limit.Rate < 0 || math.IsNaN(limit.Rate) || math.IsInf(limit.Rate, 0) || limit.Burst < 0 || limit.MaxInFlight < 0 {
+			return fmt.Errorf("%w: limit contains invalid values", ErrInvalidOption)
+		}
+		if o.limits == nil {
+			o.limits = make(map[EndpointClass]Limit)
+		}
+		o.limits[class] = limit
+		return nil
+	}
+}
+
+// Used internally in order to determine the endpoint class of a request
+func classify(method HTTPMethod, path string) EndpointClass {
+	switch {
+	case strings.HasPrefix(path, "/api/homescript/run") || strings.HasPrefix(path, "/api/homescript/lint"):
+		return EndpointClassHomescript
+	case path == "/api/power/set":
+		return EndpointClassPower
+	case method == Get:
+		return EndpointClassRead
+	}
+	return EndpointClassWrite
+}
+
+// Enforces the limit of an endpoint class
+// Combines a token bucket with a semaphore, both are safe for concurrent use
+type limiter struct {
+	// The number of tokens which are added per second, zero disables the token bucket
+	rate float64
+	// The capacity of the token bucket
+	burst float64
+	// Protects `tokens` and `last`
+	mu sync.Mutex
+	// The number of available tokens, negative if callers are waiting for tokens
+	tokens float64
+	// The time at which `tokens` was last updated
+	last time.Time
+	// Contains one element per request in flight, nil disables the semaphore
+	slots chan struct{}
+}
+
+// Used internally in order to create the limiters of a connection
+// Returns nil if no limits have been specified
+func newLimiters(limits map[EndpointClass]Limit) map[EndpointClass]*limiter {
+	if len(limits) == 0 {
+		return nil
+	}
+	limiters := make(map[EndpointClass]*limiter, len(limits))
+	for class, limit := range limits {
+		burst := math.Max(float64(limit.Burst), 1)
+		l := &limiter{
+			rate:   limit.Rate,
+			burst:  burst,
+			tokens: burst,
+			last:   time.Now(),
+		}
+		if limit.MaxInFlight > 0 {
+			l.slots = make(chan struct{}, limit.MaxInFlight)
+		}
+		limiters[class] = l
+	}
+	return limiters
+}
+
+// Used internally in order to block until a request may be sent
+// The returned function must be called once the request is no longer in flight
+/** Errors
+- nil
+- ErrCanceled
+*/
+func (c *Connection) acquire(ctx context.Context, r apiRequest) (func(), error) {
+	l := c.limiters[classify(r.method, r.path)]
+	if l == nil {
+		return func() {}, nil
+	}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, &canceledError{cause: ctx.Err()}
+		}
+	}
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			if l.slots != nil {
+				<-l.slots
+			}
+		})
+	}
+	if err := l.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// Used internally in order to take a token from the bucket, waiting until one is available
+// Tokens are reserved in the order of the calls, which means that waiting callers are served fairly
+func (l *limiter) wait(ctx context.Context) error {
+	if l.rate == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Return the reserved token, so that other callers do not wait for it
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return &canceledError{cause: ctx.Err()}
+	}
+}
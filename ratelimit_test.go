@@ -0,0 +1,118 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newLimitTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/switch/list/all", handler)
+	r.HandleFunc("/api/power/set", handler)
+
+	return httptest.NewServer(r)
+}
+
+func TestClassify(t *testing.T) {
+	assert.Equal(t, EndpointClassRead, classify(Get, "/api/switch/list/personal"))
+	assert.Equal(t, EndpointClassPower, classify(Post, "/api/power/set"))
+	assert.Equal(t, EndpointClassHomescript, classify(Post, "/api/homescript/run/live"))
+	assert.Equal(t, EndpointClassHomescript, classify(Post, "/api/homescript/lint"))
+	assert.Equal(t, EndpointClassWrite, classify(Post, "/api/homescript/add"))
+}
+
+func TestRateLimit(t *testing.T) {
+	ts := newLimitTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("[]"))
+	})
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodNone, WithLimit(EndpointClassRead, Limit{Rate: 20, Burst: 2}))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+
+	// The burst is sent immediately, the remaining requests are spaced by 50 milliseconds
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		_, err := c.GetAllSwitches()
+		assert.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 180*time.Millisecond)
+
+	// Callers which wait for a token respect their context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	for {
+		if _, err = c.GetAllSwitchesContext(ctx); err != nil {
+			break
+		}
+	}
+	assert.ErrorIs(t, err, ErrCanceled)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Other endpoint classes are not limited
+	start = time.Now()
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, c.SetPower("s1", true))
+	}
+	assert.Less(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestMaxInFlight(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	ts := newLimitTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			previous := maxInFlight.Load()
+			if current <= previous || maxInFlight.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	})
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodNone, WithLimit(EndpointClassPower, Limit{MaxInFlight: 2}))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.SetPower("s1", true))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), maxInFlight.Load())
+
+	// Every slot has been released
+	assert.Len(t, c.limiters[EndpointClassPower].slots, 0)
+}
+
+func TestLimitOptions(t *testing.T) {
+	_, err := NewConnection("http://localhost", AuthMethodNone, WithLimit(EndpointClass(42), Limit{Rate: 1}))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewConnection("http://localhost", AuthMethodNone, WithLimit(EndpointClassRead, Limit{Rate: -1}))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewConnection("http://localhost", AuthMethodNone, WithLimit(EndpointClassRead, Limit{MaxInFlight: -1}))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+}
@@ -0,0 +1,248 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Describes what a `ReconcileEvent` reports
+type ReconcileEventType uint8
+
+const (
+	// The actual power state of a switch differs from the desired one, a correction is sent
+	ReconcileDrift ReconcileEventType = iota
+	// The power state of a drifted switch has been corrected
+	ReconcileCorrected
+	// The power state of a switch could not be corrected, it is retried during the next reconciliation
+	ReconcileFailed
+	// A switch with a desired state is not visible to the user, this counts as a failure
+	ReconcileMissing
+	// The switch has failed too often and is no longer corrected
+	// It is corrected again once its desired state changes or its actual state matches the desired one
+	ReconcileGaveUp
+	// The actual state of the switches could not be retrieved, the reconciliation is retried after the interval
+	ReconcileError
+)
+
+// Returns a human-readable name of the event type, used for logging
+func (t ReconcileEventType) String() string {
+	switch t {
+	case ReconcileDrift:
+		return "drift"
+	case ReconcileCorrected:
+		return "corrected"
+	case ReconcileFailed:
+		return "failed"
+	case ReconcileMissing:
+		return "missing"
+	case ReconcileGaveUp:
+		return "gave-up"
+	case ReconcileError:
+		return "error"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(t))
+	}
+}
+
+// Is reported by a `Reconciler` for every drift and every correction
+type ReconcileEvent struct {
+	Type ReconcileEventType
+	// The id of the affected switch, empty for `ReconcileError`
+	SwitchId string
+	// The desired power state of the switch
+	Desired bool
+	// The actual state of the switch before the correction, empty if the switch is missing
+	Actual Switch
+	// The number of consecutive failures of the switch
+	Failures int
+	// The error which occurred, set for `ReconcileFailed`, `ReconcileMissing`, `ReconcileGaveUp` and `ReconcileError`
+	Err error
+}
+
+// Configures a `Reconciler`
+type ReconcilerOptions struct {
+	// How often the actual state of the switches is compared to the desired state
+	// A value of zero or less uses the default of 30 seconds
+	Interval time.Duration
+	// The number of consecutive failures after which a switch is no longer corrected
+	// A value of zero or less uses the default of 3 failures
+	MaxFailures int
+	// Configures how the corrections of a single reconciliation are sent
+	Power SetPowerManyOptions
+	// Is called for every event, may be nil
+	// Is called by the goroutine which executes `Run`, which means that it blocks the reconciliation
+	OnEvent func(event ReconcileEvent)
+}
+
+// Keeps the power state of switches at a desired state, for example in order to implement an away mode
+// Periodically reads the actual state using `GetPersonalSwitches` and only sets the power of switches which have drifted
+// Is created using `NewReconciler` and started using `Run`
+type Reconciler struct {
+	c    *Connection
+	opts ReconcilerOptions
+	// Protects `desired` and `failures`
+	mu sync.Mutex
+	// The desired power state of every switch
+	desired map[string]bool
+	// The number of consecutive failures of every switch
+	failures map[string]int
+}
+
+// Creates a reconciler which keeps the switches of the connection's user at the desired power states
+// The keys of `desired` are switch ids, switches which are not contained are never modified
+func NewReconciler(c *Connection, desired map[string]bool, opts ReconcilerOptions) *Reconciler {
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if opts.MaxFailures <= 0 {
+		opts.MaxFailures = 3
+	}
+	r := &Reconciler{
+		c:        c,
+		opts:     opts,
+		failures: make(map[string]int),
+	}
+	r.SetDesired(desired)
+	return r
+}
+
+// Replaces the desired power states, takes effect during the next reconciliation
+// The failures of switches whose desired state has changed are reset
+// Is safe to be called while the reconciler is running
+func (r *Reconciler) SetDesired(desired map[string]bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := make(map[string]bool, len(desired))
+	for id, powerOn := range desired {
+		copied[id] = powerOn
+		if previous, found := r.desired[id]; !found || previous != powerOn {
+			delete(r.failures, id)
+		}
+	}
+	r.desired = copied
+}
+
+// Reconciles the switches immediately and then every interval until `ctx` is canceled or the connection is closed
+// Failures of single reconciliations are reported as events and do not stop the reconciler
+// Must not be called multiple times concurrently
+/** Errors
+- ErrCanceled
+- ErrConnectionClosed
+*/
+func (r *Reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+	for {
+		r.Reconcile(ctx)
+		select {
+		case <-ctx.Done():
+			return &canceledError{cause: ctx.Err()}
+		case <-r.c.done:
+			return ErrConnectionClosed
+		case <-ticker.C:
+		}
+	}
+}
+
+// Performs a single reconciliation: reads the actual state and corrects every drifted switch
+// Is used by `Run`, but can also be called directly, for example after changing the desired state
+/** Errors
+- nil
+- GetPersonalSwitches errors
+- SetPowerMany errors
+*/
+func (r *Reconciler) Reconcile(ctx context.Context) error {
+	switches, err := r.c.GetPersonalSwitchesContext(ctx)
+	if err != nil {
+		if !errors.Is(err, ErrCanceled) {
+			r.emit(ReconcileEvent{Type: ReconcileError, Err: err})
+		}
+		return err
+	}
+	actual := make(map[string]Switch, len(switches))
+	for _, s := range switches {
+		actual[s.Id] = s
+	}
+
+	r.mu.Lock()
+	desired := r.desired
+	ids := make([]string, 0, len(desired))
+	for id := range desired {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	corrections := make(map[string]bool)
+	events := make([]ReconcileEvent, 0)
+	for _, id := range ids {
+		want := desired[id]
+		s, found := actual[id]
+		if found && s.PowerOn == want {
+			delete(r.failures, id)
+			continue
+		}
+		if r.failures[id] >= r.opts.MaxFailures {
+			continue
+		}
+		if !found {
+			events = append(events, r.fail(ReconcileEvent{Type: ReconcileMissing, SwitchId: id, Desired: want, Err: ErrInvalidSwitch})...)
+			continue
+		}
+		events = append(events, ReconcileEvent{Type: ReconcileDrift, SwitchId: id, Desired: want, Actual: s, Failures: r.failures[id]})
+		corrections[id] = want
+	}
+	r.mu.Unlock()
+	for _, event := range events {
+		r.emit(event)
+	}
+	if len(corrections) == 0 {
+		return nil
+	}
+
+	results, err := r.c.SetPowerMany(ctx, corrections, r.opts.Power)
+	events = events[:0]
+	r.mu.Lock()
+	for _, result := range results {
+		event := ReconcileEvent{SwitchId: result.Switch, Desired: result.PowerOn, Actual: actual[result.Switch]}
+		switch {
+		case result.Err == nil:
+			delete(r.failures, result.Switch)
+			event.Type = ReconcileCorrected
+			events = append(events, event)
+		case errors.Is(result.Err, ErrCanceled):
+			// The reconciler is stopping, this is not a failure of the switch
+		default:
+			event.Type = ReconcileFailed
+			event.Err = result.Err
+			events = append(events, r.fail(event)...)
+		}
+	}
+	r.mu.Unlock()
+	for _, event := range events {
+		r.emit(event)
+	}
+	return err
+}
+
+// Used internally in order to count a failure of a switch, `mu` must be held
+// Returns the event as well as a `ReconcileGaveUp` event if the switch has failed too often
+func (r *Reconciler) fail(event ReconcileEvent) []ReconcileEvent {
+	r.failures[event.SwitchId]++
+	event.Failures = r.failures[event.SwitchId]
+	if event.Failures < r.opts.MaxFailures {
+		return []ReconcileEvent{event}
+	}
+	gaveUp := event
+	gaveUp.Type = ReconcileGaveUp
+	return []ReconcileEvent{event, gaveUp}
+}
+
+// Used internally in order to report an event to the callback, if one is configured
+func (r *Reconciler) emit(event ReconcileEvent) {
+	if r.opts.OnEvent != nil {
+		r.opts.OnEvent(event)
+	}
+}
@@ -0,0 +1,134 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconciler(t *testing.T) {
+	var mu sync.Mutex
+	switches := map[string]bool{"a": false, "b": true, "broken": false}
+	powerRequests := 0
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/switch/list/personal", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		list := make([]Switch, 0, len(switches))
+		for id, powerOn := range switches {
+			list = append(list, Switch{Id: id, PowerOn: powerOn})
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(list))
+	})
+
+	r.HandleFunc("/api/power/set", func(w http.ResponseWriter, r *http.Request) {
+		var data struct {
+			Switch  string `json:"switch"`
+			PowerOn bool   `json:"powerOn"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&data))
+		mu.Lock()
+		defer mu.Unlock()
+		powerRequests++
+		if data.Switch == "broken" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switches[data.Switch] = data.PowerOn
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodNone)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+
+	var events []ReconcileEvent
+	reconciler := NewReconciler(c, map[string]bool{"a": true, "b": true, "broken": true, "missing": false}, ReconcilerOptions{
+		MaxFailures: 2,
+		OnEvent: func(event ReconcileEvent) {
+			events = append(events, event)
+		},
+	})
+
+	// Only drifted switches are corrected
+	assert.ErrorIs(t, reconciler.Reconcile(context.Background()), ErrPermissionDenied)
+	assert.Equal(t, 2, powerRequests)
+	assert.Len(t, events, 5)
+	assert.Equal(t, ReconcileEvent{Type: ReconcileDrift, SwitchId: "a", Desired: true, Actual: Switch{Id: "a"}}, events[0])
+	assert.Equal(t, ReconcileEvent{Type: ReconcileDrift, SwitchId: "broken", Desired: true, Actual: Switch{Id: "broken"}}, events[1])
+	assert.Equal(t, ReconcileEvent{Type: ReconcileMissing, SwitchId: "missing", Failures: 1, Err: ErrInvalidSwitch}, events[2])
+	assert.Equal(t, ReconcileCorrected, events[3].Type)
+	assert.Equal(t, "a", events[3].SwitchId)
+	assert.Equal(t, ReconcileFailed, events[4].Type)
+	assert.Equal(t, "broken", events[4].SwitchId)
+	assert.Equal(t, 1, events[4].Failures)
+	assert.ErrorIs(t, events[4].Err, ErrPermissionDenied)
+
+	// Switches are given up after too many failures
+	events = nil
+	assert.Error(t, reconciler.Reconcile(context.Background()))
+	assert.Equal(t, 3, powerRequests)
+	types := make([]ReconcileEventType, 0)
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []ReconcileEventType{ReconcileDrift, ReconcileMissing, ReconcileGaveUp, ReconcileFailed, ReconcileGaveUp}, types)
+
+	events = nil
+	assert.NoError(t, reconciler.Reconcile(context.Background()))
+	assert.Equal(t, 3, powerRequests)
+	assert.Empty(t, events)
+
+	// Changing the desired state resets the failures
+	reconciler.SetDesired(map[string]bool{"a": false, "broken": true})
+	assert.NoError(t, reconciler.Reconcile(context.Background()))
+	assert.Equal(t, 4, powerRequests)
+	assert.Equal(t, []ReconcileEventType{ReconcileDrift, ReconcileCorrected}, []ReconcileEventType{events[0].Type, events[1].Type})
+	mu.Lock()
+	assert.False(t, switches["a"])
+	mu.Unlock()
+
+	// Drift which happens while the reconciler is running is corrected
+	events = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	reconciler = NewReconciler(c, map[string]bool{"b": true}, ReconcilerOptions{
+		Interval: 10 * time.Millisecond,
+		OnEvent: func(event ReconcileEvent) {
+			if event.Type == ReconcileCorrected {
+				cancel()
+			}
+		},
+	})
+	mu.Lock()
+	switches["b"] = false
+	mu.Unlock()
+	assert.ErrorIs(t, reconciler.Run(ctx), ErrCanceled)
+	mu.Lock()
+	assert.True(t, switches["b"])
+	mu.Unlock()
+
+	// Closing the connection stops the reconciler
+	reconciler = NewReconciler(c, nil, ReconcilerOptions{Interval: 10 * time.Millisecond})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		assert.NoError(t, c.Close())
+	}()
+	assert.ErrorIs(t, reconciler.Run(context.Background()), ErrConnectionClosed)
+}
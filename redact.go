@@ -0,0 +1,47 @@
+package sdk
+
+import (
+	"errors"
+	"net/url"
+)
+
+// Query parameters which contain credentials and must never be logged or returned in errors
+var sensitiveQueryParams = []string{"username", "password", "token"}
+
+// Used internally in order to remove credentials from a URL before it is logged or returned in an error
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := *u
+	query := redacted.Query()
+	changed := false
+	for _, param := range sensitiveQueryParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+			changed = true
+		}
+	}
+	if changed {
+		redacted.RawQuery = query.Encode()
+	}
+	redacted.User = nil
+	return redacted.String()
+}
+
+// Used internally in order to remove credentials from errors which contain a URL
+// Errors returned by the HTTP client or by the URL parser are of the type `*url.Error`
+// If the URL cannot be parsed, it is replaced entirely
+func redactError(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	redacted := *urlErr
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+		redacted.URL = redactURL(u)
+	} else {
+		redacted.URL = "REDACTED"
+	}
+	return &redacted
+}
@@ -2,9 +2,14 @@ package sdk
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
 type HTTPMethod string
@@ -17,20 +22,32 @@ const (
 )
 
 // Used internally in order to act as a middleware to add authentication to a requested URI
-func (c *Connection) prepareRequest(path string, method HTTPMethod, body interface{}) (*http.Request, error) {
+// The request is bound to the provided context so that it can be canceled by the caller
+// The request is sent to the specified base URL, which is the active Smarthome server
+func (c *Connection) prepareRequest(ctx context.Context, base *url.URL, path string, method HTTPMethod, body interface{}) (*http.Request, error) {
 	// Creates a local copy of the smarthome base URL, then sets the path
-	u := c.SmarthomeURL
-	u.Path = path
+	u := endpointURLAt(base, path)
+
+	// Obtain the credentials if they are sent with every request, they could have been rotated by the credential provider
+	// Cookie authentication only requires the credentials for logging in
+	var creds credStore
+	if c.sendsCredentials() {
+		var err error
+		creds, err = c.credentials(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// If the authentication mode is set to `AuthMethodQueryPassword`, encode username and password and attach it to the URL
 	if c.authMethod == AuthMethodQueryPassword {
 		query := u.Query()
-		query.Set("username", c.credStore.Username)
-		query.Set("password", c.credStore.Password)
+		query.Set("username", creds.Username)
+		query.Set("password", creds.Password)
 		u.RawQuery = query.Encode()
 	} else if c.authMethod == AuthMethodQueryToken {
 		query := u.Query()
-		query.Set("token", c.credStore.Token)
+		query.Set("token", creds.Token)
 		u.RawQuery = query.Encode()
 	}
 
@@ -45,18 +62,221 @@ func (c *Connection) prepareRequest(path string, method HTTPMethod, body interfa
 	}
 
 	// Creates the request
-	r, err := http.NewRequest(string(method), u.String(), bytes.NewBuffer(encodedBody))
+	r, err := http.NewRequestWithContext(ctx, string(method), u.String(), bytes.NewBuffer(encodedBody))
 	if err != nil {
-		return nil, err
+		// The error could contain the URL, including credentials in its query
+		return nil, redactError(err)
 	}
 
 	// If the authentication mode is set to `AuthMethodCookiePassword` or `AuthMethodCookieToken`, add the cookie to the request
-	if c.authMethod == AuthMethodCookiePassword || c.authMethod == AuthMethodCookieToken {
-		r.AddCookie(c.sessionCookie)
+	if c.usesCookieAuth() {
+		r.AddCookie(c.cookie())
+	}
+
+	// If the authentication mode is set to `AuthMethodHeaderPassword` or `AuthMethodHeaderToken`, add the `Authorization` header
+	if c.authMethod == AuthMethodHeaderPassword {
+		r.SetBasicAuth(creds.Username, creds.Password)
+	} else if c.authMethod == AuthMethodHeaderToken {
+		r.Header.Set("Authorization", "Bearer "+creds.Token)
 	}
 
 	// Set `Content-Type` and `User-Agent`
 	r.Header.Set("Content-Type", "application/json")
-	r.Header.Set("User-Agent", fmt.Sprintf("SmarthomeSDK/%s", Version))
+	r.Header.Set("User-Agent", c.userAgent)
 	return r, nil
 }
+
+// Describes a request to the Smarthome API
+// Used internally in order to repeat a request, for example after a re-login
+type apiRequest struct {
+	// The name of the endpoint, for example `SetPower`
+	endpoint string
+	// The escaped path of the endpoint, relative to the base URL
+	path   string
+	method HTTPMethod
+	// Is encoded to JSON if not nil
+	body interface{}
+	// Limits the duration of every attempt, zero means no limit
+	timeout time.Duration
+	// The number of the current attempt, starting at 1
+	attempt int
+}
+
+// Used internally in order to send a request to the Smarthome server
+// Authentication is added to the request using `prepareRequest`
+// The endpoint name is made available to middlewares using `EndpointName`
+/** Errors
+- ErrConnFailed
+- ErrCanceled
+- ErrCircuitOpen
+- Relogin errors
+- PrepareRequest errors
+*/
+func (c *Connection) send(ctx context.Context, endpoint string, path string, method HTTPMethod, body interface{}) (*http.Response, error) {
+	return c.sendRequest(ctx, apiRequest{endpoint: endpoint, path: path, method: method, body: body})
+}
+
+// Same as `send`, but limits the duration of the request using the specified timeout
+// If the timeout is exceeded, `ErrConnFailed` is returned, cancellation of `ctx` is still reported as `ErrCanceled`
+func (c *Connection) sendTimeout(ctx context.Context, endpoint string, timeout time.Duration, path string, method HTTPMethod, body interface{}) (*http.Response, error) {
+	return c.sendRequest(ctx, apiRequest{endpoint: endpoint, path: path, method: method, body: body, timeout: timeout})
+}
+
+// Used internally in order to send a described request to the Smarthome server
+// Requests which failed due to transient errors are retried according to the connection's retry policy
+// If failover URLs are configured and the active server is unavailable, the connection switches to the first available server
+// If cookie authentication is used and the server rejects the session, the connection logs in again and retries the request once
+func (c *Connection) sendRequest(ctx context.Context, r apiRequest) (*http.Response, error) {
+	// Remember the cookie and the server of this attempt in order to detect whether they have already been replaced by another goroutine
+	sentCookie := c.cookie()
+	sentTo := c.activeIndex()
+	res, err := c.sendRetry(ctx, r)
+	if c.shouldFailover(res, err) {
+		// The failed response must not keep its connection and its in-flight slot while the servers are probed
+		if res != nil {
+			bufferBody(res)
+		}
+		switched, failoverErr := c.failover(ctx, sentTo)
+		// The request is only repeated on the new server if that is safe, like a retry
+		if failoverErr == nil && switched && (c.retryPolicy.RetryNonIdempotent || isIdempotent(r.method, r.path)) {
+			sentCookie = c.cookie()
+			res, err = c.sendRetry(ctx, r)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusUnauthorized || !c.usesCookieAuth() {
+		return res, nil
+	}
+	// The session has been rejected, most likely because the Smarthome server has been restarted
+	res.Body.Close()
+	c.logSessionRejected(ctx)
+	if err := c.relogin(ctx, sentCookie); err != nil {
+		return nil, err
+	}
+	return c.sendRetry(ctx, r)
+}
+
+// Used internally in order to perform a single attempt of a request
+// Fails fast if the circuit breaker is open, then blocks until the request is permitted by the limits of its endpoint class
+func (c *Connection) sendOnce(ctx context.Context, r apiRequest) (*http.Response, error) {
+	if err := c.allow(ctx); err != nil {
+		return nil, err
+	}
+	release, err := c.acquire(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	base := c.baseURL()
+	reqCtx, cancel := withTimeout(ctx, r.timeout)
+	req, err := c.prepareRequest(reqCtx, base, r.path, r.method, r.body)
+	if err != nil {
+		cancel()
+		release()
+		return nil, err
+	}
+	start := time.Now()
+	res, err := c.roundTrip(r.endpoint, r.attempt, req)
+	if err != nil {
+		// The request's own timeout says nothing about the availability of the server
+		timedOut := errors.Is(reqCtx.Err(), context.DeadlineExceeded) || c.clientTimedOut(err, start)
+		cancel()
+		release()
+		err = contextError(ctx, err)
+		if timedOut && errors.Is(err, ErrConnFailed) {
+			err = &timeoutError{}
+		}
+		c.recordOutcome(nil, err)
+		return nil, err
+	}
+	c.recordOutcome(res, nil)
+	recordServedBy(ctx, base)
+	// The context must remain valid and the request remains in flight until the caller has read the response body
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: func() {
+		cancel()
+		release()
+	}}
+	return res, nil
+}
+
+// Used internally in order to read the body of an unsuccessful response into memory and to close the original body
+// The size of the body is limited like in `newAPIError`, which is the only consumer of such bodies
+func bufferBody(res *http.Response) {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(body))
+}
+
+// Releases the context of a request once its response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// Used internally in order to translate an error returned by `client.Do` into an SDK error
+// If the context of the request has been canceled or its deadline was exceeded, an error matching `ErrCanceled` is returned
+// Errors which were not returned by the HTTP client, but by a middleware, are returned unchanged
+// If the server's certificate does not match the pinned fingerprints, `ErrCertificatePinMismatch` is returned
+// Otherwise, the request has failed due to network issues and `ErrConnFailed` is returned
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return &canceledError{cause: ctxErr}
+	}
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	// A pinning failure is not a network issue and must therefore not be retried
+	if errors.Is(err, ErrCertificatePinMismatch) {
+		return ErrCertificatePinMismatch
+	}
+	return ErrConnFailed
+}
+
+// Used internally in order to check whether a request failed because the timeout of the HTTP client was exceeded
+// Timeouts which occur before the client's timeout, like dial timeouts, indicate that the server is unreachable
+func (c *Connection) clientTimedOut(err error, start time.Time) bool {
+	var netErr interface{ Timeout() bool }
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		return false
+	}
+	return c.client.Timeout > 0 && time.Since(start) >= c.client.Timeout
+}
+
+// Used internally in order to limit the duration of a single request
+// A timeout of zero means that the request is not limited
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Used internally in order to create the URL of an endpoint of the active Smarthome server
+func (c *Connection) endpointURL(path string) *url.URL {
+	return endpointURLAt(c.baseURL(), path)
+}
+
+// Used internally in order to create the URL of an endpoint
+// The base URL is copied so that it is never modified, which allows concurrent requests
+// The path is resolved relative to the base URL's path, so that a prefix like `/smarthome` is preserved
+// The query of the base URL is kept and can therefore be used to specify default query parameters
+// The specified path is expected to be escaped already, for example using `url.PathEscape`
+func endpointURLAt(base *url.URL, path string) *url.URL {
+	u := *base
+	u.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + path
+	unescaped, err := url.PathUnescape(u.RawPath)
+	if err != nil {
+		// The path is not escaped properly, use it as is
+		unescaped = u.RawPath
+	}
+	u.Path = unescaped
+	return &u
+}
@@ -0,0 +1,139 @@
+package sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBasePathPrefix(t *testing.T) {
+	r := http.NewServeMux()
+
+	paths := make([]string, 0)
+	// Records every request and checks that the default query parameters are preserved
+	record := func(r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		assert.Equal(t, "de", r.URL.Query().Get("lang"))
+	}
+
+	r.HandleFunc("/smarthome/api/version", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/smarthome/api/login/token", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session"})
+		assert.NoError(t, json.NewEncoder(w).Encode(tokenLoginResponse{
+			Username:   "test",
+			TokenLabel: "prefix",
+		}))
+	})
+
+	r.HandleFunc("/smarthome/health", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.HandleFunc("/smarthome/api/homescript/get/", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		assert.Equal(t, "token", r.URL.Query().Get("token"))
+		assert.NoError(t, json.NewEncoder(w).Encode(Homescript{Owner: "test"}))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL+"/smarthome/?lang=de", AuthMethodQueryToken)
+	assert.NoError(t, err)
+	assert.NoError(t, c.TokenLogin("token"))
+	_, err = c.HealthCheck()
+	assert.NoError(t, err)
+	_, err = c.GetHomescript("a/b")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"/smarthome/api/version",
+		"/smarthome/api/login/token",
+		"/smarthome/health",
+		"/smarthome/api/homescript/get/a%2Fb",
+	}, paths)
+
+	// The base URL must not be modified by requests
+	assert.Equal(t, "/smarthome/", c.SmarthomeURL.Path)
+	assert.Equal(t, "lang=de", c.SmarthomeURL.RawQuery)
+
+	_, err = NewConnection("not a url", AuthMethodNone)
+	assert.ErrorIs(t, err, ErrInvalidURL)
+}
+
+func TestHeaderAuth(t *testing.T) {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/login/token", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session"})
+		assert.NoError(t, json.NewEncoder(w).Encode(tokenLoginResponse{
+			Username:   "test",
+			TokenLabel: "header",
+		}))
+	})
+
+	r.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session"})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.HandleFunc("/api/power/set", func(w http.ResponseWriter, r *http.Request) {
+		// Credentials must never be sent in the URL or as a cookie
+		assert.Empty(t, r.URL.RawQuery)
+		assert.Empty(t, r.Cookies())
+		if username, password, ok := r.BasicAuth(); ok {
+			assert.Equal(t, "user", username)
+			assert.Equal(t, "password", password)
+		} else {
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodHeaderToken)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, c.UserLogin("user", "password"), ErrInvalidFunctionAuthMethod)
+	assert.NoError(t, c.TokenLogin("token"))
+	label, err := c.GetTokenClientLabel()
+	assert.NoError(t, err)
+	assert.Equal(t, "header", label)
+	assert.NoError(t, c.SetPower("s1", true))
+
+	c, err = NewConnection(ts.URL, AuthMethodHeaderPassword)
+	assert.NoError(t, err)
+	assert.NoError(t, c.UserLogin("user", "password"))
+	assert.NoError(t, c.SetPower("s1", true))
+}
+
+func TestRedactError(t *testing.T) {
+	_, err := http.Get("http://127.0.0.1:0/api/power/set?token=secret&lang=de")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "secret")
+
+	redacted := redactError(err)
+	assert.NotContains(t, redacted.Error(), "secret")
+	assert.Contains(t, redacted.Error(), "token=REDACTED")
+	assert.Contains(t, redacted.Error(), "lang=de")
+}
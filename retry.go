@@ -0,0 +1,159 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Specifies how requests which failed due to transient errors are retried
+// A request is considered to have failed transiently if it returned `ErrConnFailed`
+// or if the server responded with `503 Service Unavailable` or `429 Too Many Requests`
+// The latter happens, for example, while Smarthome reconnects to its database (see `StatusDegraded`)
+type RetryPolicy struct {
+	// The maximum number of attempts, including the first one
+	// A value of 1 or less disables retries
+	MaxAttempts int
+	// The delay before the first retry
+	InitialBackoff time.Duration
+	// The upper limit of the delay between two attempts, also limits delays requested using `Retry-After`
+	// A value of zero means that the delay is not limited
+	MaxBackoff time.Duration
+	// The factor by which the delay is multiplied after every attempt
+	// Values less than 1 are treated as 1
+	Multiplier float64
+	// The fraction by which every delay is randomly increased or decreased, for example 0.2 for ±20%
+	// Prevents many clients from retrying at exactly the same time
+	Jitter float64
+	// By default, only idempotent requests are retried: read requests and `SetPower`
+	// Requests like `RunHomescriptCode` could have been executed by the server even if the response was lost
+	// Setting this to true retries every request regardless of its side effects
+	RetryNonIdempotent bool
+}
+
+// Returns a retry policy which is suitable for most applications
+// Performs up to 3 attempts with a delay starting at 200 milliseconds
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// Retries requests which failed due to transient errors using the specified policy
+// By default, requests are not retried
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) error {
+		if policy.InitialBackoff < 0 || policy.MaxBackoff < 0 || policy.Jitter < 0 || policy.Jitter > 1 {
+			return fmt.Errorf("%w: retry policy contains invalid values", ErrInvalidOption)
+		}
+		o.retryPolicy = policy
+		return nil
+	}
+}
+
+// Non-GET requests which are known to be safe to retry
+// Setting the power of a switch multiple times has the same effect as setting it once
+var idempotentPaths = map[string]bool{
+	"/api/power/set": true,
+}
+
+// Used internally in order to check if a request can be safely retried
+func isIdempotent(method HTTPMethod, path string) bool {
+	return method == Get || idempotentPaths[path]
+}
+
+// Used internally in order to check if a request should be retried using the outcome of its previous attempt
+// Returns the delay which the server requested using `Retry-After`, or zero if none was requested
+func isTransient(res *http.Response, err error) (bool, time.Duration) {
+	if err != nil {
+		return errors.Is(err, ErrConnFailed), 0
+	}
+	if res.StatusCode != http.StatusServiceUnavailable && res.StatusCode != http.StatusTooManyRequests {
+		return false, 0
+	}
+	return true, parseRetryAfter(res.Header.Get("Retry-After"))
+}
+
+// Parses the value of a `Retry-After` header which is either a number of seconds or an HTTP date
+// Returns zero if the header is empty or invalid
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// Calculates the delay before the specified retry, starting at 1 for the first retry
+func (p RetryPolicy) backoff(retry int, retryAfter time.Duration) time.Duration {
+	delay := retryAfter
+	if delay == 0 {
+		multiplier := p.Multiplier
+		if multiplier < 1 {
+			multiplier = 1
+		}
+		d := float64(p.InitialBackoff)
+		for i := 1; i < retry; i++ {
+			d *= multiplier
+			if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+				break
+			}
+		}
+		// Randomly spread the delay in order to avoid synchronized retries
+		if p.Jitter > 0 {
+			d *= 1 + p.Jitter*(2*rand.Float64()-1)
+		}
+		delay = time.Duration(d)
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// Used internally in order to send a request using the connection's retry policy
+// If all attempts have failed, the outcome of the last attempt is returned
+func (c *Connection) sendRetry(ctx context.Context, r apiRequest) (*http.Response, error) {
+	policy := c.retryPolicy
+	if !policy.RetryNonIdempotent && !isIdempotent(r.method, r.path) {
+		r.attempt = 1
+		return c.sendOnce(ctx, r)
+	}
+	for attempt := 1; ; attempt++ {
+		r.attempt = attempt
+		res, err := c.sendOnce(ctx, r)
+		transient, retryAfter := isTransient(res, err)
+		if !transient || attempt >= policy.MaxAttempts {
+			return res, err
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+		// Wait before the next attempt, unless the caller gives up
+		timer := time.NewTimer(policy.backoff(attempt, retryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, &canceledError{cause: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+}
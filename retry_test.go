@@ -0,0 +1,102 @@
+package sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	// Every endpoint fails twice before it succeeds
+	var attempts int32
+	flaky := func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1)%3 != 0 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}
+	r.HandleFunc("/api/power/set", flaky)
+	r.HandleFunc("/api/homescript/run/live", flaky)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	policy := RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+
+	c, err := NewConnection(ts.URL, AuthMethodNone, WithRetryPolicy(policy))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+
+	// `SetPower` is idempotent and therefore retried
+	assert.NoError(t, c.SetPower("s1", true))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	// Running Homescript is never retried unless this is explicitly enabled
+	atomic.StoreInt32(&attempts, 0)
+	_, err = c.RunHomescriptCode("print(1)", nil, time.Second)
+	assert.ErrorIs(t, err, ErrUnknownResponseCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+
+	policy.RetryNonIdempotent = true
+	c, err = NewConnection(ts.URL, AuthMethodNone, WithRetryPolicy(policy))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+	atomic.StoreInt32(&attempts, 0)
+	_, err = c.RunHomescriptCode("print(1)", nil, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	// Once all attempts are used up, the last response is returned
+	policy.MaxAttempts = 2
+	c, err = NewConnection(ts.URL, AuthMethodNone, WithRetryPolicy(policy))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+	atomic.StoreInt32(&attempts, 0)
+	assert.ErrorIs(t, c.SetPower("s1", true), ErrServiceUnavailable)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	_, err = NewConnection(ts.URL, AuthMethodNone, WithRetryPolicy(RetryPolicy{Jitter: 2}))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1, 0))
+	assert.Equal(t, 200*time.Millisecond, policy.backoff(2, 0))
+	assert.Equal(t, 800*time.Millisecond, policy.backoff(4, 0))
+	assert.Equal(t, time.Second, policy.backoff(10, 0))
+	// `Retry-After` overrides the calculated delay but is still limited
+	assert.Equal(t, 500*time.Millisecond, policy.backoff(1, 500*time.Millisecond))
+	assert.Equal(t, time.Second, policy.backoff(1, time.Minute))
+
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("invalid"))
+	assert.InDelta(t, float64(time.Hour), float64(parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))), float64(2*time.Second))
+}
@@ -0,0 +1,235 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A serializable snapshot of a cookie-authenticated session
+// Can be used in order to reuse a session across process restarts, for example by short-lived CLI invocations
+type SessionState struct {
+	// The base URL of the connection which created the session
+	BaseURL string `json:"baseUrl"`
+	// The base URL of the server which issued the session cookie
+	// Differs from `BaseURL` if the connection had switched to a failover server, sessions are not shared between servers
+	ServerURL string `json:"serverUrl"`
+	// The authentication method of the connection which created the session
+	AuthMethod AuthMethod `json:"authMethod"`
+	// The session cookie which was obtained during the login
+	Cookie SessionCookie `json:"cookie"`
+	// The username of the session's user
+	Username string `json:"username"`
+	// The label of the token, only set when token authentication is used
+	TokenLabel string `json:"tokenLabel"`
+	// The version of the Smarthome server when the session was created
+	ServerVersion string `json:"serverVersion"`
+}
+
+// The relevant parts of the session cookie
+type SessionCookie struct {
+	Name    string    `json:"name"`
+	Value   string    `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+// Persists a session so that it can be resumed later
+// Implementations must be safe for concurrent use by multiple goroutines
+type SessionStore interface {
+	// Returns the stored session, `ErrNoSession` is returned if no session has been stored yet
+	Load() (SessionState, error)
+	// Replaces the stored session
+	Save(state SessionState) error
+	// Removes the stored session, is called by `Logout`
+	// Must not fail if no session has been stored
+	Delete() error
+}
+
+// Stores the session as a JSON file which is only readable and writable by its owner
+// The file is replaced atomically, so that concurrent processes never read a partially written session
+type FileSessionStore struct {
+	Path string
+}
+
+func (s FileSessionStore) Load() (SessionState, error) {
+	content, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return SessionState{}, ErrNoSession
+	}
+	if err != nil {
+		return SessionState{}, err
+	}
+	var state SessionState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return SessionState{}, fmt.Errorf("could not decode session file: %w", err)
+	}
+	return state, nil
+}
+
+func (s FileSessionStore) Save(state SessionState) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, `os.CreateTemp` uses the permissions 0600
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+func (s FileSessionStore) Delete() error {
+	if err := os.Remove(s.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Saves the session to the specified store after every successful login of a cookie-authenticated connection
+// This includes automatic re-logins, so that the stored session is always up to date
+func WithSessionStore(store SessionStore) Option {
+	return func(o *options) error {
+		o.sessionStore = store
+		return nil
+	}
+}
+
+// Returns a snapshot of the current session which can be resumed later using `ResumeSession`
+// Only works on cookie-based authentication methods
+/** Errors
+- nil
+- ErrInvalidFunctionAuthMethod
+- ErrNotInitialized
+*/
+func (c *Connection) ExportSession() (SessionState, error) {
+	if !c.usesCookieAuth() {
+		return SessionState{}, ErrInvalidFunctionAuthMethod
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.ready {
+		return SessionState{}, ErrNotInitialized
+	}
+	return SessionState{
+		BaseURL:    c.SmarthomeURL.String(),
+		ServerURL:  c.endpoints[c.active].String(),
+		AuthMethod: c.authMethod,
+		Cookie: SessionCookie{
+			Name:    c.sessionCookie.Name,
+			Value:   c.sessionCookie.Value,
+			Expires: c.sessionCookie.Expires,
+		},
+		Username:      c.credStore.Username,
+		TokenLabel:    c.tokenClientName,
+		ServerVersion: c.SmarthomeVersion,
+	}, nil
+}
+
+// Can be used instead of `UserLogin` or `TokenLogin` in order to reuse a previously exported session
+// Before the connection is marked as ready, the session is validated using an authenticated request
+// If the session has expired, `ErrSessionInvalid` is returned and a normal login should be performed instead
+// The same applies if the server which issued the session is not the first available server, see `WithFailoverURLs`
+// The optional provider is used for automatic re-logins, without it the connection fails once the session is rejected
+func (c *Connection) ResumeSession(state SessionState, provider CredentialProvider) error {
+	return c.ResumeSessionContext(context.Background(), state, provider)
+}
+
+// Same as `ResumeSession`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+/** Errors
+- nil
+- ErrInvalidFunctionAuthMethod
+- ErrSessionInvalid
+- ErrConnFailed
+- Version errors
+- PrepareRequest errors
+*/
+func (c *Connection) ResumeSessionContext(ctx context.Context, state SessionState, provider CredentialProvider) error {
+	if !c.usesCookieAuth() {
+		return ErrInvalidFunctionAuthMethod
+	}
+	// The session must have been created by an equivalent connection
+	if state.AuthMethod != c.authMethod || state.BaseURL != c.SmarthomeURL.String() || state.Cookie.Value == "" {
+		return ErrSessionInvalid
+	}
+	if !state.Cookie.Expires.IsZero() && state.Cookie.Expires.Before(time.Now()) {
+		return ErrSessionInvalid
+	}
+	if err := c.checkServerVersion(ctx); err != nil {
+		return err
+	}
+	// The cookie is only valid on the server which issued it, sessions exported by older versions were always issued by `BaseURL`
+	serverURL := state.ServerURL
+	if serverURL == "" {
+		serverURL = state.BaseURL
+	}
+	if serverURL != c.ActiveURL() {
+		return ErrSessionInvalid
+	}
+
+	cookie := &http.Cookie{
+		Name:    state.Cookie.Name,
+		Value:   state.Cookie.Value,
+		Expires: state.Cookie.Expires,
+	}
+	c.mu.Lock()
+	c.sessionCookie = cookie
+	c.credStore.Username = state.Username
+	c.tokenClientName = state.TokenLabel
+	c.credProvider = provider
+	c.mu.Unlock()
+
+	// Probe the session using an authenticated request which has no side effects
+	// A single attempt is used, because a re-login must not happen during the probe
+	res, err := c.sendOnce(ctx, apiRequest{
+		endpoint: "ResumeSession",
+		path:     "/api/switch/list/personal",
+		method:   Get,
+		attempt:  1,
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+		c.mu.Lock()
+		c.ready = true
+		c.mu.Unlock()
+		return nil
+	case 401:
+		return newAPIError(res, ErrSessionInvalid)
+	case 503:
+		return newAPIError(res, ErrServiceUnavailable)
+	}
+	return newAPIError(res, ErrUnknownResponseCode)
+}
+
+// Used internally in order to save the current session to the session store, if one is configured
+// Errors are only logged, because a failure to persist the session must not break the connection
+func (c *Connection) saveSession(ctx context.Context) {
+	if c.sessionStore == nil {
+		return
+	}
+	state, err := c.ExportSession()
+	if err == nil {
+		err = c.sessionStore.Save(state)
+	}
+	if err != nil && c.logger != nil {
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "smarthome session could not be saved", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,140 @@
+package sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionResume(t *testing.T) {
+	var key atomic.Value
+	key.Store("key-1")
+	store := func() *sessions.CookieStore {
+		return sessions.NewCookieStore([]byte(key.Load().(string)))
+	}
+	var logins int32
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&logins, 1)
+		session, _ := store().Get(r, "session")
+		session.Values["valid"] = true
+		assert.NoError(t, session.Save(r, w))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.HandleFunc("/api/switch/list/personal", func(w http.ResponseWriter, r *http.Request) {
+		session, err := store().Get(r, "session")
+		if err != nil || session.Values["valid"] != true {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("[]"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	sessionStore := FileSessionStore{Path: filepath.Join(t.TempDir(), "session.json")}
+	_, err := sessionStore.Load()
+	assert.ErrorIs(t, err, ErrNoSession)
+
+	// The first invocation logs in and saves its session
+	c, err := NewConnection(ts.URL, AuthMethodCookiePassword, WithSessionStore(sessionStore))
+	assert.NoError(t, err)
+	assert.NoError(t, c.UserLogin("test", "test"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logins))
+
+	info, err := os.Stat(sessionStore.Path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// The second invocation resumes the session without logging in
+	state, err := sessionStore.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "test", state.Username)
+	assert.Equal(t, MinSmarthomeVersion, state.ServerVersion)
+
+	c2, err := NewConnection(ts.URL, AuthMethodCookiePassword)
+	assert.NoError(t, err)
+	assert.NoError(t, c2.ResumeSession(state, nil))
+	_, err = c2.GetPersonalSwitches()
+	assert.NoError(t, err)
+	username, err := c2.GetUsername()
+	assert.NoError(t, err)
+	assert.Equal(t, "test", username)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logins))
+
+	// After a restart of the server, the session can no longer be resumed
+	key.Store("key-2")
+	c3, err := NewConnection(ts.URL, AuthMethodCookiePassword)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, c3.ResumeSession(state, nil), ErrSessionInvalid)
+	assert.False(t, c3.ready)
+
+	// Sessions of other servers or authentication methods are rejected without a request
+	c4, err := NewConnection(ts.URL, AuthMethodCookieToken)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, c4.ResumeSession(state, nil), ErrSessionInvalid)
+}
+
+func TestLogoutDeletesSession(t *testing.T) {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session"})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.HandleFunc("/api/logout", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	sessionStore := FileSessionStore{Path: filepath.Join(t.TempDir(), "session.json")}
+
+	c, err := NewConnection(ts.URL, AuthMethodCookiePassword, WithSessionStore(sessionStore))
+	assert.NoError(t, err)
+	assert.NoError(t, c.UserLogin("test", "test"))
+	assert.FileExists(t, sessionStore.Path)
+	assert.NoError(t, c.Logout())
+	assert.NoFileExists(t, sessionStore.Path)
+	_, err = sessionStore.Load()
+	assert.ErrorIs(t, err, ErrNoSession)
+
+	// The stored session is removed even if the server cannot be reached
+	c, err = NewConnection(ts.URL, AuthMethodCookiePassword, WithSessionStore(sessionStore))
+	assert.NoError(t, err)
+	assert.NoError(t, c.UserLogin("test", "test"))
+	assert.FileExists(t, sessionStore.Path)
+	ts.Close()
+	assert.ErrorIs(t, c.Logout(), ErrConnFailed)
+	assert.NoFileExists(t, sessionStore.Path)
+
+	// Deleting a session which has not been stored succeeds
+	assert.NoError(t, sessionStore.Delete())
+}
@@ -1,10 +1,8 @@
 package sdk
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
 	"io"
-	"net/http"
 )
 
 // Switch Response from Smarthome
@@ -17,6 +15,16 @@ type Switch struct {
 	Watts   uint16 `json:"watts"`
 }
 
+// Is used in order to create or modify a switch
+type SwitchRequest struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	RoomId string `json:"roomId"`
+	Watts  uint16 `json:"watts"`
+	// The id of the hardware node which controls the switch
+	TargetNode string `json:"targetNode"`
+}
+
 // Returns a list of switches to which the user has access to
 /** Errors
 - nil
@@ -25,21 +33,23 @@ type Switch struct {
 - ErrReadResponseBody
 - ErrConnFailed
 - ErrNotInitialized
+- ErrConnectionClosed
 - PrepareRequest errors
 */
 func (c *Connection) GetPersonalSwitches() (switches []Switch, err error) {
-	if !c.ready {
-		return nil, ErrNotInitialized
-	}
-	req, err := c.prepareRequest("/api/switch/list/personal", Get, nil)
-	if err != nil {
+	return c.GetPersonalSwitchesContext(context.Background())
+}
+
+// Same as `GetPersonalSwitches`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) GetPersonalSwitchesContext(ctx context.Context) (switches []Switch, err error) {
+	if err := c.checkReady(); err != nil {
 		return nil, err
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := c.send(ctx, "GetPersonalSwitches", "/api/switch/list/personal", Get, nil)
 	if err != nil {
-		return nil, ErrConnFailed
+		return nil, err
 	}
+	defer res.Body.Close()
 	switch res.StatusCode {
 	case 200:
 		resBody, err := io.ReadAll(res.Body)
@@ -47,16 +57,16 @@ func (c *Connection) GetPersonalSwitches() (switches []Switch, err error) {
 			return nil, ErrReadResponseBody
 		}
 		var parsedBody []Switch
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return nil, ErrReadResponseBody
+		if err := c.decode("GetPersonalSwitches", resBody, &parsedBody); err != nil {
+			return nil, err
 		}
 		return parsedBody, nil
 	case 401:
-		return nil, ErrInvalidCredentials
+		return nil, newAPIError(res, ErrInvalidCredentials)
 	case 503:
-		return nil, ErrServiceUnavailable
+		return nil, newAPIError(res, ErrServiceUnavailable)
 	}
-	return nil, fmt.Errorf("unknown response code: %s", res.Status)
+	return nil, newAPIError(res, ErrUnknownResponseCode)
 }
 
 // Returns a list containing all switches of the target instance
@@ -66,21 +76,23 @@ func (c *Connection) GetPersonalSwitches() (switches []Switch, err error) {
 - ErrReadResponseBody
 - ErrConnFailed
 - ErrNotInitialized
+- ErrConnectionClosed
 - PrepareRequest errors
 */
 func (c *Connection) GetAllSwitches() (switches []Switch, err error) {
-	if !c.ready {
-		return nil, ErrNotInitialized
-	}
-	req, err := c.prepareRequest("/api/switch/list/all", Get, nil)
-	if err != nil {
+	return c.GetAllSwitchesContext(context.Background())
+}
+
+// Same as `GetAllSwitches`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) GetAllSwitchesContext(ctx context.Context) (switches []Switch, err error) {
+	if err := c.checkReady(); err != nil {
 		return nil, err
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
+	res, err := c.send(ctx, "GetAllSwitches", "/api/switch/list/all", Get, nil)
 	if err != nil {
-		return nil, ErrConnFailed
+		return nil, err
 	}
+	defer res.Body.Close()
 	switch res.StatusCode {
 	case 200:
 		resBody, err := io.ReadAll(res.Body)
@@ -88,14 +100,14 @@ func (c *Connection) GetAllSwitches() (switches []Switch, err error) {
 			return nil, ErrReadResponseBody
 		}
 		var parsedBody []Switch
-		if err := json.Unmarshal(resBody, &parsedBody); err != nil {
-			return nil, ErrReadResponseBody
+		if err := c.decode("GetAllSwitches", resBody, &parsedBody); err != nil {
+			return nil, err
 		}
 		return parsedBody, nil
 	case 503:
-		return nil, ErrServiceUnavailable
+		return nil, newAPIError(res, ErrServiceUnavailable)
 	}
-	return nil, fmt.Errorf("unknown response code: %s", res.Status)
+	return nil, newAPIError(res, ErrUnknownResponseCode)
 }
 
 // Sends a power request to Smarthome
@@ -103,18 +115,25 @@ func (c *Connection) GetAllSwitches() (switches []Switch, err error) {
 /** Errors
 - nil
 - ErrNotInitialized
+- ErrConnectionClosed
 - ErrConnFailed
+- ErrInvalidCredentials
 - ErrServiceUnavailable
 - ErrInvalidSwitch
 - ErrPermissionDenied
 - PrepareRequest errors
-- Unknown
+- ErrUnknownResponseCode
 */
 func (c *Connection) SetPower(switchId string, powerOn bool) error {
-	if !c.ready {
-		return ErrNotInitialized
+	return c.SetPowerContext(context.Background(), switchId, powerOn)
+}
+
+// Same as `SetPower`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) SetPowerContext(ctx context.Context, switchId string, powerOn bool) error {
+	if err := c.checkReady(); err != nil {
+		return err
 	}
-	req, err := c.prepareRequest("/api/power/set", Post, struct {
+	res, err := c.send(ctx, "SetPower", "/api/power/set", Post, struct {
 		Switch  string `json:"switch"`
 		PowerOn bool   `json:"powerOn"`
 	}{
@@ -124,21 +143,158 @@ func (c *Connection) SetPower(switchId string, powerOn bool) error {
 	if err != nil {
 		return err
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+		return nil
+	case 401:
+		return newAPIError(res, ErrInvalidCredentials)
+	case 503:
+		return newAPIError(res, ErrServiceUnavailable)
+	case 422:
+		return newAPIError(res, ErrInvalidSwitch)
+	case 403:
+		return newAPIError(res, ErrPermissionDenied)
+	}
+	return newAPIError(res, ErrUnknownResponseCode)
+}
+
+// Creates a new switch in the specified room
+// Requires the permission to modify the rooms and switches of the server
+/** Errors
+- nil
+- ErrNotInitialized
+- ErrConnectionClosed
+- ErrConnFailed
+- ErrInvalidCredentials
+- ErrPermissionDenied
+- ErrServiceUnavailable
+- PrepareRequest errors
+- ErrConflict (the id is already used by another switch)
+- ErrUnprocessableEntity (invalid room / invalid target node / invalid data)
+- ErrUnknownResponseCode
+*/
+func (c *Connection) CreateSwitch(data SwitchRequest) error {
+	return c.CreateSwitchContext(context.Background(), data)
+}
+
+// Same as `CreateSwitch`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) CreateSwitchContext(ctx context.Context, data SwitchRequest) error {
+	if err := c.checkReady(); err != nil {
+		return err
+	}
+	res, err := c.send(ctx, "CreateSwitch", "/api/switch/add", Post, data)
 	if err != nil {
-		return ErrConnFailed
+		return err
 	}
 	defer res.Body.Close()
 	switch res.StatusCode {
 	case 200:
 		return nil
+	case 401:
+		return newAPIError(res, ErrInvalidCredentials)
+	case 403:
+		return newAPIError(res, ErrPermissionDenied)
+	case 409:
+		return newAPIError(res, ErrConflict)
+	case 422:
+		return newAPIError(res, ErrUnprocessableEntity)
 	case 503:
-		return ErrServiceUnavailable
+		return newAPIError(res, ErrServiceUnavailable)
+	}
+	return newAPIError(res, ErrUnknownResponseCode)
+}
+
+// Modifies the metadata of an existing switch, the switch is identified by its id
+// Requires the permission to modify the rooms and switches of the server
+/** Errors
+- nil
+- ErrNotInitialized
+- ErrConnectionClosed
+- ErrConnFailed
+- ErrInvalidCredentials
+- ErrPermissionDenied
+- ErrServiceUnavailable
+- PrepareRequest errors
+- ErrConflict (conflicting data)
+- ErrUnprocessableEntity (invalid id / invalid target node / invalid data)
+- ErrUnknownResponseCode
+*/
+func (c *Connection) ModifySwitch(data SwitchRequest) error {
+	return c.ModifySwitchContext(context.Background(), data)
+}
+
+// Same as `ModifySwitch`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) ModifySwitchContext(ctx context.Context, data SwitchRequest) error {
+	if err := c.checkReady(); err != nil {
+		return err
+	}
+	res, err := c.send(ctx, "ModifySwitch", "/api/switch/modify", Put, data)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+		return nil
+	case 401:
+		return newAPIError(res, ErrInvalidCredentials)
+	case 403:
+		return newAPIError(res, ErrPermissionDenied)
+	case 409:
+		return newAPIError(res, ErrConflict)
 	case 422:
-		return ErrInvalidSwitch
+		return newAPIError(res, ErrUnprocessableEntity)
+	case 503:
+		return newAPIError(res, ErrServiceUnavailable)
+	}
+	return newAPIError(res, ErrUnknownResponseCode)
+}
+
+// Deletes an existing switch
+// Requires the permission to modify the rooms and switches of the server
+/** Errors
+- nil
+- ErrNotInitialized
+- ErrConnectionClosed
+- ErrConnFailed
+- ErrInvalidCredentials
+- ErrPermissionDenied
+- ErrServiceUnavailable
+- PrepareRequest errors
+- ErrConflict (the switch is still in use)
+- ErrUnprocessableEntity (invalid id)
+- ErrUnknownResponseCode
+*/
+func (c *Connection) DeleteSwitch(id string) error {
+	return c.DeleteSwitchContext(context.Background(), id)
+}
+
+// Same as `DeleteSwitch`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) DeleteSwitchContext(ctx context.Context, id string) error {
+	if err := c.checkReady(); err != nil {
+		return err
+	}
+	res, err := c.send(ctx, "DeleteSwitch", "/api/switch/delete", Delete, struct {
+		Id string `json:"id"`
+	}{id})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+		return nil
+	case 401:
+		return newAPIError(res, ErrInvalidCredentials)
 	case 403:
-		return ErrPermissionDenied
+		return newAPIError(res, ErrPermissionDenied)
+	case 409:
+		return newAPIError(res, ErrConflict)
+	case 422:
+		return newAPIError(res, ErrUnprocessableEntity)
+	case 503:
+		return newAPIError(res, ErrServiceUnavailable)
 	}
-	return fmt.Errorf("unknown response code: %s", res.Status)
+	return newAPIError(res, ErrUnknownResponseCode)
 }
@@ -0,0 +1,164 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// Is sent to the server in order to grant or revoke a switch permission
+type switchPermissionRequest struct {
+	Username string `json:"username"`
+	Switch   string `json:"switch"`
+}
+
+// Allows a user to view and use the specified switch
+// Requires the permission to manage the permissions of other users
+/** Errors
+- nil
+- ErrNotInitialized
+- ErrConnectionClosed
+- ErrConnFailed
+- ErrInvalidCredentials
+- ErrPermissionDenied
+- ErrServiceUnavailable
+- PrepareRequest errors
+- ErrConflict (the user already has the permission)
+- ErrUnprocessableEntity (invalid user / invalid switch)
+- ErrUnknownResponseCode
+*/
+func (c *Connection) GrantSwitchPermission(username string, switchId string) error {
+	return c.GrantSwitchPermissionContext(context.Background(), username, switchId)
+}
+
+// Same as `GrantSwitchPermission`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) GrantSwitchPermissionContext(ctx context.Context, username string, switchId string) error {
+	if err := c.checkReady(); err != nil {
+		return err
+	}
+	res, err := c.send(ctx, "GrantSwitchPermission", "/api/user/permissions/switch/add", Post, switchPermissionRequest{
+		Username: username,
+		Switch:   switchId,
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+		return nil
+	case 401:
+		return newAPIError(res, ErrInvalidCredentials)
+	case 403:
+		return newAPIError(res, ErrPermissionDenied)
+	case 409:
+		return newAPIError(res, ErrConflict)
+	case 422:
+		return newAPIError(res, ErrUnprocessableEntity)
+	case 503:
+		return newAPIError(res, ErrServiceUnavailable)
+	}
+	return newAPIError(res, ErrUnknownResponseCode)
+}
+
+// Removes a user's access to the specified switch
+// Requires the permission to manage the permissions of other users
+/** Errors
+- nil
+- ErrNotInitialized
+- ErrConnectionClosed
+- ErrConnFailed
+- ErrInvalidCredentials
+- ErrPermissionDenied
+- ErrServiceUnavailable
+- PrepareRequest errors
+- ErrConflict (the user does not have the permission)
+- ErrUnprocessableEntity (invalid user / invalid switch)
+- ErrUnknownResponseCode
+*/
+func (c *Connection) RevokeSwitchPermission(username string, switchId string) error {
+	return c.RevokeSwitchPermissionContext(context.Background(), username, switchId)
+}
+
+// Same as `RevokeSwitchPermission`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) RevokeSwitchPermissionContext(ctx context.Context, username string, switchId string) error {
+	if err := c.checkReady(); err != nil {
+		return err
+	}
+	res, err := c.send(ctx, "RevokeSwitchPermission", "/api/user/permissions/switch/delete", Delete, switchPermissionRequest{
+		Username: username,
+		Switch:   switchId,
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+		return nil
+	case 401:
+		return newAPIError(res, ErrInvalidCredentials)
+	case 403:
+		return newAPIError(res, ErrPermissionDenied)
+	case 409:
+		return newAPIError(res, ErrConflict)
+	case 422:
+		return newAPIError(res, ErrUnprocessableEntity)
+	case 503:
+		return newAPIError(res, ErrServiceUnavailable)
+	}
+	return newAPIError(res, ErrUnknownResponseCode)
+}
+
+// Returns the ids of the switches which the specified user is allowed to use
+// Requires the permission to manage the permissions of other users
+/** Errors
+- nil
+- ErrNotInitialized
+- ErrConnectionClosed
+- ErrConnFailed
+- ErrReadResponseBody
+- ErrInvalidCredentials
+- ErrPermissionDenied
+- ErrServiceUnavailable
+- PrepareRequest errors
+- ErrUnprocessableEntity (invalid user)
+- ErrUnknownResponseCode
+*/
+func (c *Connection) ListUserSwitchPermissions(username string) (switchIds []string, err error) {
+	return c.ListUserSwitchPermissionsContext(context.Background(), username)
+}
+
+// Same as `ListUserSwitchPermissions`, but binds the request to `ctx`: cancellation yields an error matching `ErrCanceled`
+func (c *Connection) ListUserSwitchPermissionsContext(ctx context.Context, username string) (switchIds []string, err error) {
+	if err := c.checkReady(); err != nil {
+		return nil, err
+	}
+	res, err := c.send(ctx, "ListUserSwitchPermissions", fmt.Sprintf("/api/user/permissions/switch/list/%s", url.PathEscape(username)), Get, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+		resBody, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, ErrReadResponseBody
+		}
+		var parsedBody []string
+		if err := c.decode("ListUserSwitchPermissions", resBody, &parsedBody); err != nil {
+			return nil, err
+		}
+		return parsedBody, nil
+	case 401:
+		return nil, newAPIError(res, ErrInvalidCredentials)
+	case 403:
+		return nil, newAPIError(res, ErrPermissionDenied)
+	case 422:
+		return nil, newAPIError(res, ErrUnprocessableEntity)
+	case 503:
+		return nil, newAPIError(res, ErrServiceUnavailable)
+	}
+	return nil, newAPIError(res, ErrUnknownResponseCode)
+}
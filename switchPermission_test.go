@@ -0,0 +1,108 @@
+package sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSwitchPermissions(t *testing.T) {
+	var mu sync.Mutex
+	// Maps every user to the ids of the switches which they are allowed to use
+	permissions := map[string]map[string]bool{
+		"admin": {},
+		"j.doe": {},
+	}
+	switches := map[string]bool{"lamp": true, "fan": true}
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	modify := func(w http.ResponseWriter, r *http.Request, grant bool) {
+		var data switchPermissionRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&data))
+		mu.Lock()
+		defer mu.Unlock()
+		userPermissions, found := permissions[data.Username]
+		if !found || !switches[data.Switch] {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		if userPermissions[data.Switch] == grant {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		userPermissions[data.Switch] = grant
+	}
+
+	r.HandleFunc("/api/user/permissions/switch/add", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		modify(w, r, true)
+	})
+
+	r.HandleFunc("/api/user/permissions/switch/delete", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		modify(w, r, false)
+	})
+
+	r.HandleFunc("/api/user/permissions/switch/list/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		userPermissions, found := permissions[strings.TrimPrefix(r.URL.Path, "/api/user/permissions/switch/list/")]
+		if !found {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		ids := make([]string, 0)
+		for id, granted := range userPermissions {
+			if granted {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		assert.NoError(t, json.NewEncoder(w).Encode(ids))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodNone)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+
+	ids, err := c.ListUserSwitchPermissions("j.doe")
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	assert.NoError(t, c.GrantSwitchPermission("j.doe", "lamp"))
+	assert.NoError(t, c.GrantSwitchPermission("j.doe", "fan"))
+	assert.ErrorIs(t, c.GrantSwitchPermission("j.doe", "fan"), ErrConflict)
+	assert.ErrorIs(t, c.GrantSwitchPermission("j.doe", "heater"), ErrUnprocessableEntity)
+	assert.ErrorIs(t, c.GrantSwitchPermission("nobody", "lamp"), ErrUnprocessableEntity)
+
+	ids, err = c.ListUserSwitchPermissions("j.doe")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fan", "lamp"}, ids)
+
+	assert.NoError(t, c.RevokeSwitchPermission("j.doe", "fan"))
+	assert.ErrorIs(t, c.RevokeSwitchPermission("j.doe", "fan"), ErrConflict)
+
+	ids, err = c.ListUserSwitchPermissions("j.doe")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lamp"}, ids)
+
+	_, err = c.ListUserSwitchPermissions("nobody")
+	assert.ErrorIs(t, err, ErrUnprocessableEntity)
+}
@@ -0,0 +1,154 @@
+package sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Emulates the switch administration of a Smarthome server which stores its switches in memory
+// The room `living` and the hardware node `node` exist
+func newSwitchTestServer(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	switches := make(map[string]SwitchRequest)
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/switch/list/all", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		list := make([]Switch, 0, len(switches))
+		for _, data := range switches {
+			list = append(list, Switch{Id: data.Id, Name: data.Name, RoomId: data.RoomId, Watts: data.Watts})
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(list))
+	})
+
+	// Decodes and validates the request, returns false if a response has already been sent
+	decode := func(w http.ResponseWriter, r *http.Request, data *SwitchRequest) bool {
+		if err := json.NewDecoder(r.Body).Decode(data); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return false
+		}
+		if data.Id == "" || (r.Method != http.MethodDelete && (data.Name == "" || data.TargetNode != "node")) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			assert.NoError(t, json.NewEncoder(w).Encode(GenericResponse{Success: false, Message: "invalid data"}))
+			return false
+		}
+		return true
+	}
+
+	r.HandleFunc("/api/switch/add", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		var data SwitchRequest
+		if !decode(w, r, &data) {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if data.RoomId != "living" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		if _, found := switches[data.Id]; found {
+			w.WriteHeader(http.StatusConflict)
+			assert.NoError(t, json.NewEncoder(w).Encode(GenericResponse{Success: false, Message: "id already exists"}))
+			return
+		}
+		switches[data.Id] = data
+	})
+
+	r.HandleFunc("/api/switch/modify", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		var data SwitchRequest
+		if !decode(w, r, &data) {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		existing, found := switches[data.Id]
+		if !found {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		data.RoomId = existing.RoomId
+		switches[data.Id] = data
+	})
+
+	r.HandleFunc("/api/switch/delete", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		var data SwitchRequest
+		if !decode(w, r, &data) {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if _, found := switches[data.Id]; !found {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		delete(switches, data.Id)
+	})
+
+	return httptest.NewServer(r)
+}
+
+func TestSwitchAdministration(t *testing.T) {
+	ts := newSwitchTestServer(t)
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodNone)
+	assert.NoError(t, err)
+
+	// The connection must be initialized first
+	assert.ErrorIs(t, c.CreateSwitch(SwitchRequest{}), ErrNotInitialized)
+	assert.NoError(t, c.Connect())
+
+	lamp := SwitchRequest{
+		Id:         "lamp",
+		Name:       "Lamp",
+		RoomId:     "living",
+		Watts:      40,
+		TargetNode: "node",
+	}
+	assert.NoError(t, c.CreateSwitch(lamp))
+	switches, err := c.GetAllSwitches()
+	assert.NoError(t, err)
+	assert.Equal(t, []Switch{{Id: "lamp", Name: "Lamp", RoomId: "living", Watts: 40}}, switches)
+
+	// Duplicate ids are rejected
+	err = c.CreateSwitch(lamp)
+	assert.ErrorIs(t, err, ErrConflict)
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "id already exists", apiErr.Response.Message)
+
+	// Invalid rooms and target nodes are rejected
+	assert.ErrorIs(t, c.CreateSwitch(SwitchRequest{Id: "fan", Name: "Fan", RoomId: "kitchen", TargetNode: "node"}), ErrUnprocessableEntity)
+	assert.ErrorIs(t, c.CreateSwitch(SwitchRequest{Id: "fan", Name: "Fan", RoomId: "living", TargetNode: "other"}), ErrUnprocessableEntity)
+
+	lamp.Name = "Ceiling Lamp"
+	lamp.Watts = 60
+	assert.NoError(t, c.ModifySwitch(lamp))
+	switches, err = c.GetAllSwitches()
+	assert.NoError(t, err)
+	assert.Equal(t, []Switch{{Id: "lamp", Name: "Ceiling Lamp", RoomId: "living", Watts: 60}}, switches)
+	assert.ErrorIs(t, c.ModifySwitch(SwitchRequest{Id: "fan", Name: "Fan", TargetNode: "node"}), ErrUnprocessableEntity)
+
+	assert.NoError(t, c.DeleteSwitch("lamp"))
+	assert.ErrorIs(t, c.DeleteSwitch("lamp"), ErrUnprocessableEntity)
+	switches, err = c.GetAllSwitches()
+	assert.NoError(t, err)
+	assert.Empty(t, switches)
+}
@@ -0,0 +1,164 @@
+package sdk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Collects the TLS configuration of a connection
+type tlsOptions struct {
+	// If set, replaces the system's root CA pool
+	rootCAs *x509.CertPool
+	// Are presented to the server if it requests a client certificate
+	certificates []tls.Certificate
+	// SHA-256 fingerprints of which at least one must match a certificate presented by the server
+	pins [][]byte
+	// The minimum TLS version, zero means the default of `crypto/tls`
+	minVersion uint16
+}
+
+// Used internally in order to check if any TLS option has been set
+func (t tlsOptions) isSet() bool {
+	return t.rootCAs != nil || len(t.certificates) > 0 || len(t.pins) > 0 || t.minVersion != 0
+}
+
+// Verifies the server's certificates using the specified root CAs instead of the system's CA pool
+// Can be used for Smarthome servers which use certificates issued by a private CA
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(o *options) error {
+		if pool == nil {
+			return fmt.Errorf("%w: root CA pool must not be nil", ErrInvalidOption)
+		}
+		o.tls.rootCAs = pool
+		return nil
+	}
+}
+
+// Same as `WithRootCAs`, but reads the root CAs from a PEM-encoded file
+func WithRootCAFile(path string) Option {
+	return func(o *options) error {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%w: could not read root CA file: %s", ErrInvalidOption, err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return fmt.Errorf("%w: root CA file does not contain any PEM-encoded certificate", ErrInvalidOption)
+		}
+		o.tls.rootCAs = pool
+		return nil
+	}
+}
+
+// Presents the specified client certificate if the server or a proxy in front of it enforces mutual TLS
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(o *options) error {
+		o.tls.certificates = append(o.tls.certificates, cert)
+		return nil
+	}
+}
+
+// Same as `WithClientCertificate`, but reads the certificate and its private key from PEM-encoded files
+func WithClientCertificateFiles(certFile string, keyFile string) Option {
+	return func(o *options) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("%w: could not load client certificate: %s", ErrInvalidOption, err.Error())
+		}
+		o.tls.certificates = append(o.tls.certificates, cert)
+		return nil
+	}
+}
+
+// Only accepts servers which present a certificate with one of the specified SHA-256 fingerprints
+// A fingerprint is the hex-encoded SHA-256 hash of a DER-encoded certificate, colons are allowed
+// Every certificate of the verified chains is checked, which allows pinning an intermediate or a root CA, even if the server does not send it
+// Certificates which the server presents, but which are not part of a verified chain, are never matched
+// Pinning is performed in addition to the normal certificate verification
+func WithCertificatePins(fingerprints ...string) Option {
+	return func(o *options) error {
+		for _, fingerprint := range fingerprints {
+			pin, err := hex.DecodeString(strings.ReplaceAll(fingerprint, ":", ""))
+			if err != nil || len(pin) != sha256.Size {
+				return fmt.Errorf("%w: invalid SHA-256 fingerprint `%s`", ErrInvalidOption, fingerprint)
+			}
+			o.tls.pins = append(o.tls.pins, pin)
+		}
+		return nil
+	}
+}
+
+// Rejects servers which do not support at least the specified TLS version, for example `tls.VersionTLS13`
+func WithMinTLSVersion(version uint16) Option {
+	return func(o *options) error {
+		if version < tls.VersionTLS10 || version > tls.VersionTLS13 {
+			return fmt.Errorf("%w: unsupported TLS version %#04x", ErrInvalidOption, version)
+		}
+		o.tls.minVersion = version
+		return nil
+	}
+}
+
+// Returns the hex-encoded SHA-256 fingerprint of a certificate, as expected by `WithCertificatePins`
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Used internally in order to apply the TLS options to a transport
+// The transport is cloned, the specified transport is never modified
+// Only `*http.Transport` can be configured, other transports are rejected
+func (t tlsOptions) apply(transport http.RoundTripper) (http.RoundTripper, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	httpTransport, ok := transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("%w: TLS options require the transport to be an `*http.Transport`", ErrInvalidOption)
+	}
+	httpTransport = httpTransport.Clone()
+	config := &tls.Config{}
+	if httpTransport.TLSClientConfig != nil {
+		config = httpTransport.TLSClientConfig.Clone()
+	}
+	if t.rootCAs != nil {
+		config.RootCAs = t.rootCAs
+	}
+	if len(t.certificates) > 0 {
+		config.Certificates = append(config.Certificates, t.certificates...)
+	}
+	if t.minVersion != 0 {
+		config.MinVersion = t.minVersion
+	}
+	if len(t.pins) > 0 {
+		pins := t.pins
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			// Only verified chains are trusted, the server could append arbitrary certificates to the ones it presents
+			chains := state.VerifiedChains
+			if len(chains) == 0 && len(state.PeerCertificates) > 0 {
+				// Verification is disabled, only the server's own certificate can be trusted
+				chains = [][]*x509.Certificate{state.PeerCertificates[:1]}
+			}
+			for _, chain := range chains {
+				for _, cert := range chain {
+					sum := sha256.Sum256(cert.Raw)
+					for _, pin := range pins {
+						if bytes.Equal(sum[:], pin) {
+							return nil
+						}
+					}
+				}
+			}
+			return ErrCertificatePinMismatch
+		}
+	}
+	httpTransport.TLSClientConfig = config
+	return httpTransport, nil
+}
@@ -0,0 +1,231 @@
+package sdk
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Creates a self-signed client certificate and writes it and its key to PEM files
+func newClientCertificate(t *testing.T) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "smarthome-sdk-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return cert, certFile, keyFile
+}
+
+func TestTLS(t *testing.T) {
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "session"})
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.HandleFunc("/api/power/set", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	clientCert, certFile, keyFile := newClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	// The server enforces mutual TLS
+	ts := httptest.NewUnstartedServer(r)
+	ts.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	rootCAFile := filepath.Join(t.TempDir(), "ca.crt")
+	assert.NoError(t, os.WriteFile(rootCAFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600))
+
+	// Private CA, client certificate and pinning are applied to login, health, version and API requests
+	c, err := NewConnection(
+		ts.URL,
+		AuthMethodCookiePassword,
+		WithRootCAFile(rootCAFile),
+		WithClientCertificateFiles(certFile, keyFile),
+		WithCertificatePins(CertificateFingerprint(ts.Certificate())),
+		WithMinTLSVersion(tls.VersionTLS12),
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, c.UserLogin("test", "test"))
+	_, err = c.HealthCheck()
+	assert.NoError(t, err)
+	assert.NoError(t, c.SetPower("s1", true))
+
+	// Without the private CA, the server's certificate is rejected
+	c, err = NewConnection(ts.URL, AuthMethodNone, WithClientCertificateFiles(certFile, keyFile))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, c.Connect(), ErrConnFailed)
+
+	// Without the client certificate, the server rejects the connection
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	c, err = NewConnection(ts.URL, AuthMethodNone, WithRootCAs(pool))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, c.Connect(), ErrConnFailed)
+
+	// A wrong pin is reported as such
+	c, err = NewConnection(
+		ts.URL,
+		AuthMethodNone,
+		WithRootCAs(pool),
+		WithClientCertificateFiles(certFile, keyFile),
+		WithCertificatePins(CertificateFingerprint(clientCert)),
+	)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, c.Connect(), ErrCertificatePinMismatch)
+
+	// Invalid options are rejected
+	_, err = NewConnection(ts.URL, AuthMethodNone, WithCertificatePins("invalid"))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewConnection(ts.URL, AuthMethodNone, WithMinTLSVersion(0x0200))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewConnection(ts.URL, AuthMethodNone, WithTransport(&countingTransport{}), WithRootCAs(pool))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+}
+
+// Creates a certificate signed by the specified parent, a self-signed CA if parent is nil
+func newCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert, key
+}
+
+func TestTLSPinIssuingCA(t *testing.T) {
+	ca, caKey := newCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "smarthome-sdk-test-ca"},
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, nil)
+	leaf, leafKey := newCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "smarthome"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}, ca, caKey)
+
+	r := http.NewServeMux()
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// The server only presents its leaf certificate, not the CA which issued it
+	ts := httptest.NewUnstartedServer(r)
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{leaf.Raw}, PrivateKey: leafKey, Leaf: leaf}},
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	// Pinning the issuing CA is accepted
+	c, err := NewConnection(ts.URL, AuthMethodNone, WithRootCAs(pool), WithCertificatePins(CertificateFingerprint(ca)))
+	assert.NoError(t, err)
+	_, err = c.HealthCheck()
+	assert.NoError(t, err)
+
+	// Pinning the leaf is still accepted
+	c, err = NewConnection(ts.URL, AuthMethodNone, WithRootCAs(pool), WithCertificatePins(CertificateFingerprint(leaf)))
+	assert.NoError(t, err)
+	_, err = c.HealthCheck()
+	assert.NoError(t, err)
+
+	// A CA which is not part of the chain is rejected
+	other, otherKey := newCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(3),
+		Subject:               pkix.Name{CommonName: "smarthome-sdk-test-other-ca"},
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, nil)
+	c, err = NewConnection(ts.URL, AuthMethodNone, WithRootCAs(pool), WithCertificatePins(CertificateFingerprint(other)))
+	assert.NoError(t, err)
+	_, err = c.HealthCheck()
+	assert.ErrorIs(t, err, ErrCertificatePinMismatch)
+
+	// Appending the pinned CA to a chain issued by another trusted CA does not bypass the pin
+	otherLeaf, otherLeafKey := newCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(4),
+		Subject:      pkix.Name{CommonName: "smarthome"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}, other, otherKey)
+	impostor := httptest.NewUnstartedServer(r)
+	impostor.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{otherLeaf.Raw, ca.Raw}, PrivateKey: otherLeafKey, Leaf: otherLeaf}},
+	}
+	impostor.StartTLS()
+	defer impostor.Close()
+	pool.AddCert(other)
+	c, err = NewConnection(impostor.URL, AuthMethodNone, WithRootCAs(pool), WithCertificatePins(CertificateFingerprint(ca)))
+	assert.NoError(t, err)
+	_, err = c.HealthCheck()
+	assert.ErrorIs(t, err, ErrCertificatePinMismatch)
+}
@@ -0,0 +1,206 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Describes which kind of change a `SwitchEvent` reports
+type SwitchEventType uint8
+
+const (
+	// A switch has become visible to the user, is also emitted for every switch of the initial snapshot
+	SwitchAdded SwitchEventType = iota
+	// A switch has been deleted or the user's permission to it has been revoked
+	SwitchRemoved
+	// The power state of a switch has changed
+	SwitchPowerChanged
+	// The state of the switches could not be retrieved, the watcher keeps trying using an increasing delay
+	SwitchWatchError
+)
+
+// Returns a human-readable name of the event type, used for logging
+func (t SwitchEventType) String() string {
+	switch t {
+	case SwitchAdded:
+		return "added"
+	case SwitchRemoved:
+		return "removed"
+	case SwitchPowerChanged:
+		return "power-changed"
+	case SwitchWatchError:
+		return "error"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(t))
+	}
+}
+
+// A change of the user's switches which was detected by `WatchSwitches`
+type SwitchEvent struct {
+	Type SwitchEventType
+	// The state of the switch after the change, the last known state for `SwitchRemoved`
+	// Empty for `SwitchWatchError`
+	Switch Switch
+	// The error which occurred, only set for `SwitchWatchError`
+	Err error
+}
+
+// The upper limit of the delay between two attempts after the state of the switches could not be retrieved
+// The delay is never shorter than the interval of the watcher
+const maxWatchBackoff = time.Minute
+
+// Watches the switches of the current user and emits an event for every change
+// The returned channel first receives a `SwitchAdded` event for every switch of the initial snapshot
+// The switches are polled using `GetPersonalSwitches` every `interval`, every poll counts as a read request, see `WithLimit`
+// Only polling is used because the Smarthome server exposes no event stream, changes are therefore detected with a delay of up to `interval`
+// The channel is closed once `ctx` is canceled or the connection is closed, events must be received in order not to block the watcher
+// An interval of zero or less uses the default of 5 seconds
+/** Errors
+- nil
+- GetPersonalSwitches errors
+*/
+func (c *Connection) WatchSwitches(ctx context.Context, interval time.Duration) (<-chan SwitchEvent, error) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	// The initial snapshot is retrieved synchronously so that the caller can handle a misconfiguration immediately
+	switches, err := c.GetPersonalSwitchesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w := &switchWatcher{
+		c:        c,
+		interval: interval,
+		events:   make(chan SwitchEvent),
+		state:    make(map[string]Switch),
+	}
+	go w.run(ctx, switches)
+	return w.events, nil
+}
+
+// Polls the user's switches and emits the differences between consecutive snapshots
+type switchWatcher struct {
+	c        *Connection
+	interval time.Duration
+	events   chan SwitchEvent
+	// The latest known state of every switch, is only accessed by the watcher's goroutine
+	state map[string]Switch
+}
+
+// Used internally in order to watch the switches until the context is canceled or the connection is closed
+func (w *switchWatcher) run(ctx context.Context, initial []Switch) {
+	defer close(w.events)
+	// Closing the connection stops the watcher, this also aborts a running poll
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-w.c.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if !w.update(ctx, initial) {
+		return
+	}
+	failures := 0
+	delay := w.interval
+	for {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		err := w.poll(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		delay = w.interval
+		if err != nil {
+			failures++
+			delay = watchBackoff(w.interval, failures)
+			if !w.emit(ctx, SwitchEvent{Type: SwitchWatchError, Err: err}) {
+				return
+			}
+		} else {
+			failures = 0
+		}
+	}
+}
+
+// Used internally in order to retrieve a snapshot of the switches and to emit its differences
+func (w *switchWatcher) poll(ctx context.Context) error {
+	switches, err := w.c.GetPersonalSwitchesContext(ctx)
+	if err != nil {
+		return err
+	}
+	w.update(ctx, switches)
+	return nil
+}
+
+// Used internally in order to emit the differences between the known state and a new snapshot
+// Returns false if the watcher has been stopped while emitting
+func (w *switchWatcher) update(ctx context.Context, switches []Switch) bool {
+	sort.Slice(switches, func(i, j int) bool { return switches[i].Id < switches[j].Id })
+	current := make(map[string]Switch, len(switches))
+	events := make([]SwitchEvent, 0)
+	for _, s := range switches {
+		current[s.Id] = s
+		previous, found := w.state[s.Id]
+		if !found {
+			events = append(events, SwitchEvent{Type: SwitchAdded, Switch: s})
+		} else if previous.PowerOn != s.PowerOn {
+			events = append(events, SwitchEvent{Type: SwitchPowerChanged, Switch: s})
+		}
+	}
+	removed := make([]string, 0)
+	for id := range w.state {
+		if _, found := current[id]; !found {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+	for _, id := range removed {
+		events = append(events, SwitchEvent{Type: SwitchRemoved, Switch: w.state[id]})
+	}
+	w.state = current
+	for _, event := range events {
+		if !w.emit(ctx, event) {
+			return false
+		}
+	}
+	return true
+}
+
+// Used internally in order to send an event to the receiver of the channel
+// Returns false if the watcher has been stopped before the event was received
+func (w *switchWatcher) emit(ctx context.Context, event SwitchEvent) bool {
+	select {
+	case w.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Used internally in order to calculate the delay after consecutive failures
+// The delay doubles with every failure, starting at the interval
+func watchBackoff(interval time.Duration, failures int) time.Duration {
+	limit := maxWatchBackoff
+	if interval > limit {
+		limit = interval
+	}
+	delay := interval
+	for i := 1; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		return limit
+	}
+	return delay
+}
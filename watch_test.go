@@ -0,0 +1,183 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Emulates a Smarthome server whose switches can be modified by the test
+type watchTestServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	switches []Switch
+	failing  bool
+}
+
+func newWatchTestServer(t *testing.T, switches []Switch) *watchTestServer {
+	s := &watchTestServer{switches: switches}
+
+	r := http.NewServeMux()
+
+	r.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewEncoder(w).Encode(VersionResponse{
+			Version:   MinSmarthomeVersion,
+			GoVersion: "go1.18",
+		}))
+	})
+
+	r.HandleFunc("/api/switch/list/personal", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(s.switches))
+	})
+
+	s.Server = httptest.NewServer(r)
+	return s
+}
+
+// Modifies the switches of the server
+func (s *watchTestServer) modify(modify func(switches []Switch) []Switch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.switches = modify(s.switches)
+}
+
+// Receives the next event or fails the test after a timeout
+func nextSwitchEvent(t *testing.T, events <-chan SwitchEvent) SwitchEvent {
+	select {
+	case event, ok := <-events:
+		assert.True(t, ok, "the channel has been closed")
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event has been received")
+		return SwitchEvent{}
+	}
+}
+
+func TestWatchSwitches(t *testing.T) {
+	ts := newWatchTestServer(t, []Switch{
+		{Id: "b", Name: "B"},
+		{Id: "a", Name: "A", PowerOn: true},
+	})
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodNone)
+	assert.NoError(t, err)
+	_, err = c.WatchSwitches(context.Background(), time.Millisecond)
+	assert.ErrorIs(t, err, ErrNotInitialized)
+	assert.NoError(t, c.Connect())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := c.WatchSwitches(ctx, 10*time.Millisecond)
+	assert.NoError(t, err)
+
+	// The initial snapshot is reported in alphabetical order
+	assert.Equal(t, SwitchEvent{Type: SwitchAdded, Switch: Switch{Id: "a", Name: "A", PowerOn: true}}, nextSwitchEvent(t, events))
+	assert.Equal(t, SwitchEvent{Type: SwitchAdded, Switch: Switch{Id: "b", Name: "B"}}, nextSwitchEvent(t, events))
+
+	ts.modify(func(switches []Switch) []Switch {
+		switches[0].PowerOn = true
+		return switches
+	})
+	assert.Equal(t, SwitchEvent{Type: SwitchPowerChanged, Switch: Switch{Id: "b", Name: "B", PowerOn: true}}, nextSwitchEvent(t, events))
+
+	ts.modify(func(switches []Switch) []Switch {
+		return []Switch{switches[0], {Id: "c", Name: "C"}}
+	})
+	assert.Equal(t, SwitchEvent{Type: SwitchAdded, Switch: Switch{Id: "c", Name: "C"}}, nextSwitchEvent(t, events))
+	assert.Equal(t, SwitchEvent{Type: SwitchRemoved, Switch: Switch{Id: "a", Name: "A", PowerOn: true}}, nextSwitchEvent(t, events))
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchSwitchesLimits(t *testing.T) {
+	ts := newWatchTestServer(t, []Switch{{Id: "a"}})
+	defer ts.Close()
+
+	// The watcher outlives the default timeout and must not keep the only read slot
+	c, err := NewConnection(ts.URL, AuthMethodNone,
+		WithDefaultTimeout(100*time.Millisecond),
+		WithLimit(EndpointClassRead, Limit{MaxInFlight: 1}),
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := c.WatchSwitches(ctx, 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, SwitchAdded, nextSwitchEvent(t, events).Type)
+
+	deadline := time.Now().Add(300 * time.Millisecond)
+	for powerOn := true; time.Now().Before(deadline); powerOn = !powerOn {
+		ts.modify(func(switches []Switch) []Switch {
+			switches[0].PowerOn = powerOn
+			return switches
+		})
+		event := nextSwitchEvent(t, events)
+		assert.Equal(t, SwitchPowerChanged, event.Type, event.Err)
+
+		// Other read requests are not blocked by the watcher
+		requestCtx, requestCancel := context.WithTimeout(context.Background(), time.Second)
+		_, err = c.GetPersonalSwitchesContext(requestCtx)
+		requestCancel()
+		assert.NoError(t, err)
+	}
+}
+
+func TestWatchSwitchesErrors(t *testing.T) {
+	ts := newWatchTestServer(t, []Switch{{Id: "a"}})
+	defer ts.Close()
+
+	c, err := NewConnection(ts.URL, AuthMethodNone)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Connect())
+
+	events, err := c.WatchSwitches(context.Background(), 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, SwitchAdded, nextSwitchEvent(t, events).Type)
+
+	// Errors are reported and the watcher keeps trying
+	ts.mu.Lock()
+	ts.failing = true
+	ts.mu.Unlock()
+	event := nextSwitchEvent(t, events)
+	assert.Equal(t, SwitchWatchError, event.Type)
+	assert.ErrorIs(t, event.Err, ErrServiceUnavailable)
+
+	ts.modify(func(switches []Switch) []Switch {
+		return nil
+	})
+	ts.mu.Lock()
+	ts.failing = false
+	ts.mu.Unlock()
+	for event = nextSwitchEvent(t, events); event.Type == SwitchWatchError; event = nextSwitchEvent(t, events) {
+	}
+	assert.Equal(t, SwitchEvent{Type: SwitchRemoved, Switch: Switch{Id: "a"}}, event)
+
+	// Closing the connection stops the watcher
+	assert.NoError(t, c.Close())
+	for range events {
+	}
+}
+
+func TestWatchBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, watchBackoff(time.Second, 1))
+	assert.Equal(t, 4*time.Second, watchBackoff(time.Second, 3))
+	assert.Equal(t, maxWatchBackoff, watchBackoff(time.Second, 20))
+	assert.Equal(t, 2*time.Minute, watchBackoff(2*time.Minute, 5))
+}